@@ -0,0 +1,104 @@
+package gtfs
+
+// Reports whether an attribution has no agency_id, route_id or trip_id and so
+// applies to the whole feed
+func isFeedWide(a *Attribution) bool {
+	return a.AgencyId == "" && a.RouteId == "" && a.TripId == ""
+}
+
+// The producer, operator and authority flags of an attribution
+func attributionRoles(a *Attribution) [3]bool {
+	return [3]bool{a.IsProducer == "1", a.IsOperator == "1", a.IsAuthority == "1"}
+}
+
+// Picks, for each of producer, operator and authority separately, the
+// attributions from the first level that has any with that role. Levels run
+// from most to least specific and the feed-wide attributions come last.
+func (f *Feed) resolveAttributions(levels ...func(a *Attribution) bool) []*Attribution {
+	levels = append(levels, isFeedWide)
+
+	picked := make(map[*Attribution]bool)
+	for role := 0; role < 3; role++ {
+		for _, level := range levels {
+			found := false
+			for _, a := range f.Attributions {
+				if level(a) && attributionRoles(a)[role] {
+					picked[a] = true
+					found = true
+				}
+			}
+
+			if found {
+				break
+			}
+		}
+	}
+
+	out := make([]*Attribution, 0, len(picked))
+	for _, a := range f.Attributions {
+		if picked[a] {
+			out = append(out, a)
+		}
+	}
+
+	return out
+}
+
+// The attributions which apply to every part of the feed, in feed order
+func (f *Feed) FeedAttributions() []*Attribution {
+	return f.resolveAttributions()
+}
+
+// The attributions for an agency, in feed order. For each of producer,
+// operator and authority, the agency's own attributions with that role are
+// used if there are any, otherwise the feed-wide ones.
+func (f *Feed) AttributionsForAgency(agencyID string) []*Attribution {
+	return f.resolveAttributions(f.agencyAttributions(agencyID))
+}
+
+// The attributions for a route, in feed order. As with AttributionsForAgency,
+// each role falls back from the route to its agency and then to the feed.
+func (f *Feed) AttributionsForRoute(routeID string) []*Attribution {
+	return f.resolveAttributions(f.routeAttributions(routeID)...)
+}
+
+// The attributions for a trip, in feed order. As with AttributionsForAgency,
+// each role falls back from the trip to its route, its agency and then the
+// feed.
+func (f *Feed) AttributionsForTrip(tripID string) []*Attribution {
+	levels := []func(a *Attribution) bool{
+		func(a *Attribution) bool { return tripID != "" && a.TripId == tripID },
+	}
+
+	if t := f.Trip(tripID); t != nil {
+		levels = append(levels, f.routeAttributions(t.RouteId)...)
+	}
+
+	return f.resolveAttributions(levels...)
+}
+
+func (f *Feed) agencyAttributions(agencyID string) func(a *Attribution) bool {
+	return func(a *Attribution) bool {
+		return agencyID != "" && a.AgencyId == agencyID
+	}
+}
+
+// The route's own level followed by its agency's
+func (f *Feed) routeAttributions(routeID string) []func(a *Attribution) bool {
+	levels := []func(a *Attribution) bool{
+		func(a *Attribution) bool { return routeID != "" && a.RouteId == routeID },
+	}
+
+	r := f.Route(routeID)
+	if r == nil {
+		return levels
+	}
+
+	// Routes may leave out agency_id when there is only one agency
+	agencyID := r.AgencyId
+	if agencyID == "" && len(f.Agencies) == 1 {
+		agencyID = f.Agencies[0].Id
+	}
+
+	return append(levels, f.agencyAttributions(agencyID))
+}
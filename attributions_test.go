@@ -0,0 +1,98 @@
+package gtfs
+
+import (
+	"testing"
+)
+
+// Routes with and without an agency_id, and a trip on each
+var (
+	testAttributedRoutes = `route_id,agency_id,route_short_name,route_long_name,route_type
+A,FunBus,17,Mission,3
+B,,18,Valencia,3`
+	testAttributedTrips = `route_id,service_id,trip_id
+A,WE,AWE1
+B,WE,BWE1`
+)
+
+func attributionIds(as []*Attribution) string {
+	s := ""
+	for i, a := range as {
+		if i > 0 {
+			s += ","
+		}
+		s += a.Id
+	}
+
+	return s
+}
+
+func TestAttributions(t *testing.T) {
+	feed := loadTestFeed(t, map[string]string{
+		"routes.txt": testAttributedRoutes,
+		"trips.txt":  testAttributedTrips,
+		"attributions.txt": `attribution_id,agency_id,route_id,trip_id,organization_name,is_producer,is_operator,is_authority
+PROD,,,,Data Co,1,,
+AUTH,,,,Transit Region,,,1
+OPA,FunBus,,,Fun Bus Operations,,1,
+OPR,,A,,Mission Contractor,,1,
+TRIP,,,AWE1,Trip Data,1,1,`,
+	})
+
+	assert(t, len(feed.Attributions) == 5, "Wrong number of attributions")
+
+	ids := attributionIds(feed.FeedAttributions())
+	assert(t, ids == "PROD,AUTH", "Wrong feed attributions "+ids)
+
+	ids = attributionIds(feed.AttributionsForAgency("FunBus"))
+	assert(t, ids == "PROD,AUTH,OPA", "Wrong agency attributions "+ids)
+
+	ids = attributionIds(feed.AttributionsForRoute("A"))
+	assert(t, ids == "PROD,AUTH,OPR", "Wrong route attributions "+ids)
+
+	ids = attributionIds(feed.AttributionsForRoute("B"))
+	assert(t, ids == "PROD,AUTH,OPA", "Route without agency_id should use the only agency "+ids)
+
+	ids = attributionIds(feed.AttributionsForTrip("AWE1"))
+	assert(t, ids == "AUTH,TRIP", "Wrong trip attributions "+ids)
+
+	ids = attributionIds(feed.AttributionsForTrip("BWE1"))
+	assert(t, ids == "PROD,AUTH,OPA", "Wrong fallback trip attributions "+ids)
+
+	ids = attributionIds(feed.AttributionsForTrip("NOPE"))
+	assert(t, ids == "PROD,AUTH", "Unknown trip should get feed attributions "+ids)
+}
+
+func TestValidateAttributions(t *testing.T) {
+	feed := loadTestFeed(t, map[string]string{
+		"routes.txt": testAttributedRoutes,
+		"trips.txt":  testAttributedTrips,
+		"attributions.txt": `attribution_id,agency_id,route_id,trip_id,organization_name,is_producer,is_operator,is_authority,attribution_email
+A1,,,,Data Co,1,,,
+A2,,,,No Role,0,,,
+A3,FunBus,A,,Both,1,,,
+A4,,Q,,Missing Route,,2,,
+A5,,,,Bad Email,1,,,nobody`,
+	})
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	codes := []struct {
+		row   int
+		field string
+		code  string
+	}{
+		{2, "is_producer, is_operator, is_authority", "missing_attribution_role"},
+		{3, "agency_id, route_id, trip_id", "multiple_attribution_entities"},
+		{4, "route_id", "foreign_key_violation"},
+		{4, "is_producer, is_operator, is_authority", "missing_attribution_role"},
+		{4, "is_operator", "invalid_enum_value"},
+		{5, "attribution_email", "invalid_email"},
+	}
+	for _, c := range codes {
+		assert(t, findingCode(findings, "attributions.txt", c.row, c.field) == c.code, "Missing "+c.code+" for "+c.field)
+	}
+	assert(t, len(findings) == len(codes), "Wrong number of findings")
+}
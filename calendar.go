@@ -0,0 +1,282 @@
+package gtfs
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Exception types from calendar_dates.txt
+const (
+	ExceptionAdded   = "1"
+	ExceptionRemoved = "2"
+)
+
+type calendarService struct {
+	start, end Date
+	days       [7]bool
+}
+
+// Answers which services run on which dates, combining the weekly patterns of
+// calendar.txt with the added and removed dates of calendar_dates.txt
+type Calendar struct {
+	services   map[string]*calendarService
+	exceptions map[string]map[Date]bool
+	ids        []string
+}
+
+// Builds a calendar, failing if any date, weekday flag or exception type is
+// invalid
+func NewCalendar(services []*Service, exceptions []*ServiceException) (*Calendar, error) {
+	c := &Calendar{
+		services:   make(map[string]*calendarService),
+		exceptions: make(map[string]map[Date]bool),
+	}
+
+	for _, s := range services {
+		cs := &calendarService{}
+
+		var err error
+		cs.start, err = ParseDate(s.StartDate)
+		if err != nil {
+			return nil, errors.New("Service " + s.ServiceId + " has invalid start_date " + s.StartDate)
+		}
+
+		cs.end, err = ParseDate(s.EndDate)
+		if err != nil {
+			return nil, errors.New("Service " + s.ServiceId + " has invalid end_date " + s.EndDate)
+		}
+
+		flags := [7]string{s.Sunday, s.Monday, s.Tuesday, s.Wednesday, s.Thursday, s.Friday, s.Saturday}
+		for i, flag := range flags {
+			switch flag {
+			case "0":
+			case "1":
+				cs.days[i] = true
+			default:
+				return nil, errors.New("Service " + s.ServiceId + " has invalid " + time.Weekday(i).String() + " flag " + flag)
+			}
+		}
+
+		if _, ok := c.services[s.ServiceId]; ok {
+			return nil, errors.New("Service " + s.ServiceId + " is defined more than once")
+		}
+		c.services[s.ServiceId] = cs
+	}
+
+	for _, e := range exceptions {
+		date, err := ParseDate(e.Date)
+		if err != nil {
+			return nil, errors.New("Service " + e.ServiceId + " has invalid exception date " + e.Date)
+		}
+
+		var added bool
+		switch e.ExceptionType {
+		case ExceptionAdded:
+			added = true
+		case ExceptionRemoved:
+			added = false
+		default:
+			return nil, errors.New("Service " + e.ServiceId + " has invalid exception_type " + e.ExceptionType)
+		}
+
+		if c.exceptions[e.ServiceId] == nil {
+			c.exceptions[e.ServiceId] = make(map[Date]bool)
+		}
+		c.exceptions[e.ServiceId][date] = added
+	}
+
+	seen := make(map[string]bool)
+	for id := range c.services {
+		seen[id] = true
+	}
+	for id := range c.exceptions {
+		seen[id] = true
+	}
+	for id := range seen {
+		c.ids = append(c.ids, id)
+	}
+	sort.Strings(c.ids)
+
+	return c, nil
+}
+
+// Builds the calendar for the feed's calendar.txt and calendar_dates.txt
+func (f *Feed) Calendar() (*Calendar, error) {
+	return NewCalendar(f.Services, f.ServiceExceptions)
+}
+
+// Reports whether the service runs on date. Exceptions take precedence over
+// the weekly pattern.
+func (c *Calendar) IsActive(serviceID string, date Date) bool {
+	if added, ok := c.exceptions[serviceID][date]; ok {
+		return added
+	}
+
+	s := c.services[serviceID]
+	if s == nil || date.Before(s.start) || date.After(s.end) {
+		return false
+	}
+
+	return s.days[date.Weekday()]
+}
+
+// The IDs of every service running on date, sorted
+func (c *Calendar) ActiveServices(date Date) []string {
+	active := make([]string, 0)
+	for _, id := range c.ids {
+		if c.IsActive(id, date) {
+			active = append(active, id)
+		}
+	}
+
+	return active
+}
+
+// The number of days in a month
+func daysIn(year int, month time.Month) int {
+	switch month {
+	case time.February:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case time.April, time.June, time.September, time.November:
+		return 30
+	}
+
+	return 31
+}
+
+// The day after d, without the cost of going through time.Time
+func nextDay(d Date) Date {
+	d.Day++
+	if d.Day > daysIn(d.Year, d.Month) {
+		d.Day = 1
+		d.Month++
+		if d.Month > time.December {
+			d.Month = time.January
+			d.Year++
+		}
+	}
+
+	return d
+}
+
+// The day before d, without the cost of going through time.Time
+func prevDay(d Date) Date {
+	d.Day--
+	if d.Day < 1 {
+		d.Month--
+		if d.Month < time.January {
+			d.Month = time.December
+			d.Year--
+		}
+		d.Day = daysIn(d.Year, d.Month)
+	}
+
+	return d
+}
+
+// Every date the service runs on, in order
+func (c *Calendar) DatesForService(serviceID string) []Date {
+	dates := make([]Date, 0)
+
+	if s := c.services[serviceID]; s != nil {
+		wd := s.start.Weekday()
+		for d := s.start; !d.After(s.end); d = nextDay(d) {
+			added, ok := c.exceptions[serviceID][d]
+			if (ok && added) || (!ok && s.days[wd]) {
+				dates = append(dates, d)
+			}
+			wd = (wd + 1) % 7
+		}
+	}
+
+	for d, added := range c.exceptions[serviceID] {
+		if !added {
+			continue
+		}
+
+		if s := c.services[serviceID]; s != nil && !d.Before(s.start) && !d.After(s.end) {
+			continue
+		}
+		dates = append(dates, d)
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// The first and last dates the service runs on, found without listing every
+// date. ok is false if it never runs.
+func (c *Calendar) ServiceDateRange(serviceID string) (first, last Date, ok bool) {
+	include := func(d Date) {
+		if !ok || d.Before(first) {
+			first = d
+		}
+		if !ok || d.After(last) {
+			last = d
+		}
+		ok = true
+	}
+
+	for d, added := range c.exceptions[serviceID] {
+		if added {
+			include(d)
+		}
+	}
+
+	s := c.services[serviceID]
+	if s == nil || s.days == [7]bool{} {
+		return first, last, ok
+	}
+
+	// Only removed dates can stop a day of the week from being active, so
+	// each search ends within a week of the last removed date it meets
+	wd := s.start.Weekday()
+	for d := s.start; !d.After(s.end); d = nextDay(d) {
+		if s.days[wd] && c.IsActive(serviceID, d) {
+			include(d)
+			break
+		}
+		wd = (wd + 1) % 7
+	}
+
+	wd = s.end.Weekday()
+	for d := s.end; !d.Before(s.start); d = prevDay(d) {
+		if s.days[wd] && c.IsActive(serviceID, d) {
+			include(d)
+			break
+		}
+		wd = (wd + 6) % 7
+	}
+
+	return first, last, ok
+}
+
+// The IDs of every service in calendar.txt or calendar_dates.txt, sorted
+func (c *Calendar) ServiceIds() []string {
+	return c.ids
+}
+
+// The first and last dates on which any service runs. ok is false if no
+// service ever runs.
+func (c *Calendar) DateRange() (start, end Date, ok bool) {
+	for _, id := range c.ids {
+		first, last, active := c.ServiceDateRange(id)
+		if !active {
+			continue
+		}
+
+		if !ok || first.Before(start) {
+			start = first
+		}
+		if !ok || last.After(end) {
+			end = last
+		}
+		ok = true
+	}
+
+	return start, end, ok
+}
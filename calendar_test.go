@@ -0,0 +1,127 @@
+package gtfs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCalendar(t *testing.T) *Calendar {
+	services, err := DecodeAs[Service](strings.NewReader(`service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date
+WE,0,0,0,0,0,1,1,20060701,20060731
+WD,1,1,1,1,1,0,0,20060701,20060731`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exceptions, err := DecodeAs[ServiceException](strings.NewReader(`service_id,date,exception_type
+WD,20060703,2
+WE,20060703,1
+WD,20060704,2
+WE,20060704,1
+HOL,20060904,1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewCalendar(services, exceptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestCalendarIsActive(t *testing.T) {
+	c := testCalendar(t)
+
+	assert(t, c.IsActive("WE", Date{2006, time.July, 1}), "WE should run on a Saturday")
+	assert(t, !c.IsActive("WD", Date{2006, time.July, 1}), "WD should not run on a Saturday")
+	assert(t, c.IsActive("WD", Date{2006, time.July, 5}), "WD should run on a Wednesday")
+	assert(t, !c.IsActive("WD", Date{2006, time.July, 3}), "WD should be removed on 3 July")
+	assert(t, c.IsActive("WE", Date{2006, time.July, 3}), "WE should be added on 3 July")
+	assert(t, !c.IsActive("WE", Date{2006, time.August, 5}), "WE should not run after its end date")
+	assert(t, c.IsActive("HOL", Date{2006, time.September, 4}), "HOL should run on its added date")
+	assert(t, !c.IsActive("XX", Date{2006, time.July, 5}), "Unknown service should not run")
+}
+
+func TestCalendarActiveServices(t *testing.T) {
+	c := testCalendar(t)
+
+	active := c.ActiveServices(Date{2006, time.July, 4})
+	assert(t, len(active) == 1 && active[0] == "WE", "Wrong active services on 4 July")
+
+	active = c.ActiveServices(Date{2006, time.July, 5})
+	assert(t, len(active) == 1 && active[0] == "WD", "Wrong active services on 5 July")
+
+	assert(t, strings.Join(c.ServiceIds(), ",") == "HOL,WD,WE", "Wrong service ids")
+}
+
+func TestCalendarDates(t *testing.T) {
+	c := testCalendar(t)
+
+	dates := c.DatesForService("WE")
+	assert(t, len(dates) == 12, "Wrong number of WE dates")
+	assert(t, dates[0] == Date{2006, time.July, 1}, "Wrong first WE date")
+	assert(t, dates[2] == Date{2006, time.July, 3}, "Added date should be in order")
+
+	dates = c.DatesForService("WD")
+	assert(t, len(dates) == 19, "Wrong number of WD dates")
+
+	start, end, ok := c.DateRange()
+	assert(t, ok, "Calendar should have a date range")
+	assert(t, start == Date{2006, time.July, 1}, "Wrong calendar start "+start.String())
+	assert(t, end == Date{2006, time.September, 4}, "Wrong calendar end "+end.String())
+}
+
+func TestServiceDateRange(t *testing.T) {
+	services := []*Service{
+		{ServiceId: "LONG", Monday: "1", Tuesday: "0", Wednesday: "0", Thursday: "0", Friday: "0", Saturday: "0", Sunday: "0", StartDate: "20060701", EndDate: "20991231"},
+		{ServiceId: "NONE", Monday: "0", Tuesday: "0", Wednesday: "0", Thursday: "0", Friday: "0", Saturday: "0", Sunday: "0", StartDate: "20060701", EndDate: "20991231"},
+	}
+	exceptions := []*ServiceException{
+		{ServiceId: "LONG", Date: "20060703", ExceptionType: ExceptionRemoved},
+		{ServiceId: "LONG", Date: "20991228", ExceptionType: ExceptionRemoved},
+		{ServiceId: "NONE", Date: "20070101", ExceptionType: ExceptionAdded},
+	}
+
+	c, err := NewCalendar(services, exceptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, last, ok := c.ServiceDateRange("LONG")
+	assert(t, ok && first == Date{2006, time.July, 10}, "Wrong first date skipping a removed Monday "+first.String())
+	assert(t, last == Date{2099, time.December, 21}, "Wrong last date skipping a removed Monday "+last.String())
+
+	dates := c.DatesForService("LONG")
+	assert(t, dates[0] == first && dates[len(dates)-1] == last, "Range does not match the listed dates")
+
+	first, last, ok = c.ServiceDateRange("NONE")
+	assert(t, ok && first == Date{2007, time.January, 1} && last == first, "Wrong range for a service with only an added date")
+
+	_, _, ok = c.ServiceDateRange("XX")
+	assert(t, !ok, "Unknown service should have no range")
+
+	for d, i := (Date{1999, time.December, 25}), 0; i < 1000; d, i = nextDay(d), i+1 {
+		assert(t, nextDay(d) == d.AddDays(1) && prevDay(d) == d.AddDays(-1), "Wrong neighbouring days for "+d.String())
+	}
+}
+
+func TestCalendarInvalid(t *testing.T) {
+	_, err := NewCalendar([]*Service{{ServiceId: "A", Monday: "yes", Tuesday: "0", Wednesday: "0", Thursday: "0", Friday: "0", Saturday: "0", Sunday: "0", StartDate: "20060701", EndDate: "20060731"}}, nil)
+	assert(t, err != nil, "Invalid weekday flag accepted")
+
+	_, err = NewCalendar(nil, []*ServiceException{{ServiceId: "A", Date: "20060701", ExceptionType: "3"}})
+	assert(t, err != nil, "Invalid exception type accepted")
+}
+
+func TestDateArithmetic(t *testing.T) {
+	d := Date{2006, time.February, 28}
+
+	assert(t, d.AddDays(1) == Date{2006, time.March, 1}, "Wrong date after adding a day")
+	assert(t, d.AddDays(-59) == Date{2005, time.December, 31}, "Wrong date after subtracting days")
+	assert(t, d.Weekday() == time.Tuesday, "Wrong weekday")
+	assert(t, d.Before(d.AddDays(1)) && d.AddDays(1).After(d), "Wrong date ordering")
+	assert(t, d.Compare(d) == 0, "Wrong date comparison")
+}
@@ -0,0 +1,67 @@
+package gtfs
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// The character encoding of a file. GTFS requires UTF-8 but some exports are
+// in one of the older single byte encodings.
+type Charset int
+
+const (
+	UTF8 Charset = iota
+	Latin1
+	Windows1252
+)
+
+// Windows-1252 differs from Latin-1 only in 0x80 to 0x9F. The five unassigned
+// bytes map to the matching C1 control characters, as Latin-1 would.
+var windows1252High = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+func (c Charset) decodeByte(b byte) rune {
+	if c == Windows1252 && b >= 0x80 && b < 0xA0 {
+		return windows1252High[b-0x80]
+	}
+
+	return rune(b)
+}
+
+// Converts a single byte encoding to UTF-8 as it is read
+type transcoder struct {
+	r       io.Reader
+	charset Charset
+	in      []byte
+	out     []byte
+	pos     int
+	err     error
+}
+
+func newTranscoder(r io.Reader, c Charset) *transcoder {
+	return &transcoder{r: r, charset: c, in: make([]byte, 4096)}
+}
+
+func (t *transcoder) Read(p []byte) (int, error) {
+	for t.pos == len(t.out) {
+		if t.err != nil {
+			return 0, t.err
+		}
+
+		n, err := t.r.Read(t.in)
+		t.err = err
+		t.out = t.out[:0]
+		t.pos = 0
+		for _, b := range t.in[:n] {
+			t.out = utf8.AppendRune(t.out, t.charset.decodeByte(b))
+		}
+	}
+
+	n := copy(p, t.out[t.pos:])
+	t.pos += n
+	return n, nil
+}
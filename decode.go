@@ -0,0 +1,638 @@
+package gtfs
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func getFieldIndexForStruct(t reflect.Type, name string) (int, error) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("gtfs_name") == name {
+			return i, nil
+		}
+	}
+
+	return -1, errors.New("Field not found " + name)
+}
+
+func getIfFieldRequired(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("gtfs_name") == name {
+			if f.Tag.Get("gtfs_required") == "true" {
+				return true
+			} else {
+				return false
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns the index of the field tagged gtfs_extra:"true", or -1
+func getExtraFieldIndex(t reflect.Type) int {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("gtfs_extra") == "true" {
+			return i
+		}
+	}
+
+	return -1
+}
+
+const utf8BOM = "\ufeff"
+
+// Reported when a required value is empty
+var ErrMissingValue = errors.New("Missing required value")
+
+// An error at a particular place in a file. Err is ErrMissingValue, a
+// *csv.ParseError when the CSV itself is malformed, or whatever the
+// underlying reader returned.
+type DecodeError struct {
+	File string
+
+	// 1-based line number in the file, 0 if unknown
+	Line int
+
+	// 0-based column index, -1 if the error is not about a single column
+	Column int
+
+	// The gtfs_name of the column, if known
+	Field string
+
+	Value string
+	Err   error
+}
+
+// The "file:line: " prefix of messages, or "line N: " when the file is not
+// known. Whichever of the two is unknown is left out.
+func position(file string, line int) string {
+	if line == 0 {
+		if file == "" {
+			return ""
+		}
+		return file + ": "
+	}
+
+	if file == "" {
+		return "line " + strconv.Itoa(line) + ": "
+	}
+
+	return file + ":" + strconv.Itoa(line) + ": "
+}
+
+func (e *DecodeError) Error() string {
+	s := position(e.File, e.Line)
+	if e.Field != "" {
+		s += e.Field + ": "
+	}
+
+	s += e.Err.Error()
+	if e.Value != "" {
+		s += " " + strconv.Quote(e.Value)
+	}
+
+	return s
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Returned when the header of a file lacks columns tagged
+// gtfs_required:"true"
+type MissingColumnsError struct {
+	File    string
+	Columns []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	s := "Missing required columns " + strings.Join(e.Columns, ", ")
+	if e.File != "" {
+		s = e.File + ": " + s
+	}
+
+	return s
+}
+
+// Something the decoder ignored or worked around while reading a file
+type Warning struct {
+	File string
+
+	// The line where it first happened
+	Line int
+
+	Column  string
+	Message string
+
+	// How many times it happened. Ragged rows of the same kind share one
+	// warning, so this can be more than 1.
+	Count int
+
+	// The lines it happened on, up to the first maxWarningLines
+	Lines []int
+}
+
+// The most lines kept on a warning shared by many rows
+const maxWarningLines = 1000
+
+func (w Warning) String() string {
+	s := w.Message
+	if w.Column != "" {
+		s += " " + w.Column
+	}
+
+	if w.Count > 1 {
+		s += " (" + strconv.Itoa(w.Count) + " rows)"
+	}
+
+	return position(w.File, w.Line) + s
+}
+
+// Reads the rows of a GTFS CSV file one at a time, so large files such as
+// stop_times.txt do not have to be held in memory.
+//
+//	d := NewDecoder(r, &StopTime{})
+//	for d.Next() {
+//		st := d.Row().(*StopTime)
+//	}
+//	if d.Err() != nil { ... }
+//
+// Columns which the row type does not know are skipped and reported by
+// Warnings. If the row type has a map[string]string field tagged
+// gtfs_extra:"true" the unknown values are stored there, and they are also
+// available from Extra.
+//
+// By default decoding stops at the first bad row. In lenient mode bad rows are
+// collected by Errors and decoding carries on with the next row, so one pass
+// can report every problem in a file.
+//
+// String fields receive values as they are, while fields of other types are
+// parsed: integers, floats, bools as 0 or 1, and any type implementing
+// encoding.TextUnmarshaler such as ServiceTime, Date and Color. Empty values
+// leave the field as its zero value. Use a pointer field such as *ServiceTime
+// or *Color to tell an empty value from a zero one, as empty values leave it
+// nil.
+//
+// A leading byte order mark is skipped and spaces around column names are
+// ignored. Rows need not have the same number of fields as the header: missing
+// trailing fields are empty and extra fields are dropped. Each of these gives
+// one warning, with a Count of the rows affected and their Lines.
+type Decoder struct {
+	// Name of the file being read, used in warnings and errors
+	File string
+
+	// Record row errors and carry on rather than stopping
+	Lenient bool
+
+	// In lenient mode, still return rows which had errors
+	KeepInvalid bool
+
+	// In lenient mode, the most errors to keep, 0 for no limit. Errors past
+	// the limit are counted but not kept.
+	MaxErrors int
+
+	// Encoding of the input, which is converted to UTF-8
+	Charset Charset
+
+	r        io.Reader
+	c        *csv.Reader
+	t        reflect.Type
+	columns  []string
+	fields   []int
+	required []bool
+	extra    int
+	row      interface{}
+	rowExtra map[string]string
+	warnings []Warning
+
+	// Index in warnings of the warning for each kind of ragged row
+	ragged map[string]int
+
+	errors     []error
+	errorCount int
+	err        error
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Reports whether setField can store into a field of type t
+func isSupportedFieldType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			return false
+		}
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// Parses s into v according to the type of v. Empty values leave v as the
+// zero value, which is nil for pointer fields.
+func setField(v reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setField(p.Elem(), s); err != nil {
+			return err
+		}
+
+		v.Set(p)
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch s {
+		case "0":
+			v.SetBool(false)
+		case "1":
+			v.SetBool(true)
+		default:
+			return errors.New("Invalid boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err.(*strconv.NumError).Err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err.(*strconv.NumError).Err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err.(*strconv.NumError).Err
+		}
+		v.SetFloat(n)
+	default:
+		return errors.New("Unsupported field type " + v.Type().String())
+	}
+
+	return nil
+}
+
+// Checks that t is a struct type with at least one gtfs_name tag
+func checkRowType(t reflect.Type) error {
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("Row type must be a struct")
+	}
+
+	extra := getExtraFieldIndex(t)
+	if extra >= 0 && t.Field(extra).Type != reflect.TypeOf(map[string]string{}) {
+		return errors.New("Field " + t.Field(extra).Name + " tagged gtfs_extra must be a map[string]string")
+	}
+
+	tagged := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("gtfs_name") == "" {
+			continue
+		}
+
+		if !isSupportedFieldType(f.Type) {
+			return errors.New("Field " + f.Name + " has unsupported type " + f.Type.String())
+		}
+		tagged = true
+	}
+
+	if !tagged {
+		return errors.New("Row type " + t.Name() + " has no gtfs_name tags")
+	}
+
+	return nil
+}
+
+// Creates a decoder producing rows of the same type as rowtype, which must be
+// a pointer to a struct with gtfs_name tags
+func NewDecoder(r io.Reader, rowtype interface{}) *Decoder {
+	d := &Decoder{r: r}
+
+	t := reflect.TypeOf(rowtype)
+	if t == nil || t.Kind() != reflect.Ptr {
+		d.err = errors.New("Row type must be a pointer to a struct")
+		return d
+	}
+
+	d.t = t.Elem()
+	d.err = checkRowType(d.t)
+	if d.err == nil {
+		d.extra = getExtraFieldIndex(d.t)
+	}
+
+	return d
+}
+
+func (d *Decoder) readHeader() error {
+	r := d.r
+	if d.Charset != UTF8 {
+		r = newTranscoder(r, d.Charset)
+	}
+
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+	d.c = csv.NewReader(br)
+	d.c.FieldsPerRecord = -1
+
+	columns, err := d.c.Read()
+	if err != nil {
+		return d.readError(err)
+	}
+
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	if len(columns) == 0 {
+		return errors.New("No fields")
+	}
+
+	d.columns = columns
+	d.fields = make([]int, len(columns))
+	d.required = make([]bool, len(columns))
+
+	for i := 0; i < len(columns); i++ {
+		d.fields[i], err = getFieldIndexForStruct(d.t, columns[i])
+		if err != nil {
+			line, _ := d.c.FieldPos(i)
+			d.warnings = append(d.warnings, Warning{d.File, line, columns[i], "Ignoring unknown column", 1, []int{line}})
+			continue
+		}
+
+		d.required[i] = getIfFieldRequired(d.t, columns[i])
+	}
+
+	var missing []string
+	for i := 0; i < d.t.NumField(); i++ {
+		f := d.t.Field(i)
+		if f.Tag.Get("gtfs_required") != "true" {
+			continue
+		}
+
+		name := f.Tag.Get("gtfs_name")
+		found := false
+		for _, c := range columns {
+			if c == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingColumnsError{d.File, missing}
+	}
+
+	return nil
+}
+
+func (d *Decoder) readError(err error) error {
+	line := 0
+
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		line = pe.Line
+	}
+
+	return &DecodeError{d.File, line, -1, "", "", err}
+}
+
+// Advances to the next row, returning false at the end of the input or on
+// error
+func (d *Decoder) Next() bool {
+	d.row = nil
+	d.rowExtra = nil
+	if d.err != nil {
+		return false
+	}
+
+	if d.columns == nil {
+		d.err = d.readHeader()
+		if d.err != nil {
+			return false
+		}
+	}
+
+	for {
+		row, err := d.c.Read()
+		if err == io.EOF {
+			return false
+		} else if err != nil {
+			var pe *csv.ParseError
+			if d.Lenient && errors.As(err, &pe) {
+				d.addError(d.readError(err))
+				continue
+			}
+
+			d.err = d.readError(err)
+			return false
+		}
+
+		o, errs := d.decodeRow(row)
+		if len(errs) > 0 {
+			if !d.Lenient {
+				d.err = errs[0]
+				return false
+			}
+
+			for _, e := range errs {
+				d.addError(e)
+			}
+
+			if !d.KeepInvalid {
+				continue
+			}
+		}
+
+		d.row = o.Interface()
+		return true
+	}
+}
+
+func (d *Decoder) decodeRow(row []string) (reflect.Value, []error) {
+	var errs []error
+
+	line, _ := d.c.FieldPos(0)
+	if len(row) > len(d.columns) {
+		d.warnRagged(line, "Ignoring fields past the end of the header")
+	} else if len(row) < len(d.columns) {
+		d.warnRagged(line, "Treating missing trailing fields as empty")
+	}
+
+	d.rowExtra = nil
+	o := reflect.New(d.t)
+	for i := 0; i < len(d.columns); i++ {
+		value := ""
+		if i < len(row) {
+			value = row[i]
+		}
+
+		if d.fields[i] < 0 {
+			if d.rowExtra == nil {
+				d.rowExtra = make(map[string]string)
+			}
+			d.rowExtra[d.columns[i]] = value
+			continue
+		}
+
+		if d.required[i] {
+			if value == "" {
+				fieldLine := line
+				if i < len(row) {
+					fieldLine, _ = d.c.FieldPos(i)
+				}
+				errs = append(errs, &DecodeError{d.File, fieldLine, i, d.columns[i], value, ErrMissingValue})
+				continue
+			}
+		}
+
+		err := setField(o.Elem().Field(d.fields[i]), value)
+		if err != nil {
+			fieldLine := line
+			if i < len(row) {
+				fieldLine, _ = d.c.FieldPos(i)
+			}
+			errs = append(errs, &DecodeError{d.File, fieldLine, i, d.columns[i], value, err})
+		}
+	}
+
+	if d.extra >= 0 && d.rowExtra != nil {
+		o.Elem().Field(d.extra).Set(reflect.ValueOf(d.rowExtra))
+	}
+
+	return o, errs
+}
+
+// Counts a ragged row against the one warning kept for its kind, so files
+// where every row has a trailing comma do not build up a warning per row
+func (d *Decoder) warnRagged(line int, message string) {
+	if i, ok := d.ragged[message]; ok {
+		w := &d.warnings[i]
+		w.Count++
+		if len(w.Lines) < maxWarningLines {
+			w.Lines = append(w.Lines, line)
+		}
+		return
+	}
+
+	if d.ragged == nil {
+		d.ragged = make(map[string]int)
+	}
+	d.ragged[message] = len(d.warnings)
+	d.warnings = append(d.warnings, Warning{d.File, line, "", message, 1, []int{line}})
+}
+
+func (d *Decoder) addError(err error) {
+	d.errorCount++
+	if d.MaxErrors <= 0 || len(d.errors) < d.MaxErrors {
+		d.errors = append(d.errors, err)
+	}
+}
+
+// The row read by the last call to Next, a pointer to a new struct of the
+// decoder's row type
+func (d *Decoder) Row() interface{} {
+	return d.row
+}
+
+// The values of unknown columns in the row read by the last call to Next,
+// keyed by column name, or nil if the file has no unknown columns
+func (d *Decoder) Extra() map[string]string {
+	return d.rowExtra
+}
+
+// The row errors collected so far in lenient mode, at most MaxErrors of them
+func (d *Decoder) Errors() []error {
+	return d.errors
+}
+
+// The number of row errors found so far in lenient mode, including any beyond
+// MaxErrors
+func (d *Decoder) ErrorCount() int {
+	return d.errorCount
+}
+
+// Everything ignored so far, such as unknown columns
+func (d *Decoder) Warnings() []Warning {
+	return d.warnings
+}
+
+// The first error encountered, if any
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func Decode(r io.Reader, rowtype interface{}) ([]interface{}, error) {
+	d := NewDecoder(r, rowtype)
+	output := make([]interface{}, 0)
+
+	for d.Next() {
+		output = append(output, d.Row())
+	}
+
+	if d.Err() != nil {
+		return nil, d.Err()
+	}
+
+	return output, nil
+}
+
+// Decodes every row of r into a new T, which must be a struct with gtfs_name
+// tags
+func DecodeAs[T any](r io.Reader) ([]*T, error) {
+	return decodeAll[T](NewDecoder(r, new(T)))
+}
+
+func decodeAll[T any](d *Decoder) ([]*T, error) {
+	output := make([]*T, 0)
+
+	for d.Next() {
+		output = append(output, d.Row().(*T))
+	}
+
+	if d.Err() != nil {
+		return nil, d.Err()
+	}
+
+	return output, nil
+}
@@ -0,0 +1,411 @@
+package gtfs
+
+import (
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	s := `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+AWE1,0:06:10,0:06:10,S1,1
+AWE1,,,S2,2
+AWE1,0:06:20,0:06:30,S3,3`
+
+	d := NewDecoder(strings.NewReader(s), &StopTime{})
+	count := 0
+	for d.Next() {
+		st := d.Row().(*StopTime)
+		count++
+
+		assert(t, st.TripId == "AWE1", "Wrong stop time trip id")
+		assert(t, st.StopSequence == strings.TrimPrefix(st.StopId, "S"), "Wrong stop time stop sequence")
+	}
+
+	if d.Err() != nil {
+		t.Fatal(d.Err())
+	}
+
+	assert(t, count == 3, "Wrong number of rows")
+	assert(t, d.Row() == nil, "Row should be nil after the last row")
+}
+
+func TestDecoderMissingRequired(t *testing.T) {
+	s := `trip_id,stop_id,stop_sequence
+AWE1,S1,1
+AWE1,,2
+AWE1,S3,3`
+
+	d := NewDecoder(strings.NewReader(s), &StopTime{})
+	count := 0
+	for d.Next() {
+		count++
+	}
+
+	assert(t, count == 1, "Decoder should stop at the bad row")
+	assert(t, d.Err() != nil, "Missing required field not reported")
+	assert(t, !d.Next(), "Next should keep returning false after an error")
+}
+
+func TestDecodeAs(t *testing.T) {
+	s := `shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence
+A_shp,37.61956,-122.48161,1
+A_shp,37.64430,-122.41070,2`
+
+	out, err := DecodeAs[ShapePoint](strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(out) == 2, "Wrong length of output")
+	assert(t, out[1].PtSequence == "2", "Wrong shape point sequence")
+}
+
+func TestDecodeAsUntagged(t *testing.T) {
+	type untagged struct {
+		Name string
+	}
+
+	_, err := DecodeAs[untagged](strings.NewReader("name\nx"))
+	assert(t, err != nil, "Untagged struct should be rejected")
+
+	_, err = DecodeAs[string](strings.NewReader("name\nx"))
+	assert(t, err != nil, "Non-struct type should be rejected")
+}
+
+func TestDecoderUnknownColumns(t *testing.T) {
+	s := `stop_id,stop_name,tts_stop_name,stop_lat,stop_lon,stop_access
+S1,Mission St.,Mission Street,37.728631,-122.431282,1`
+
+	d := NewDecoder(strings.NewReader(s), &Stop{})
+	d.File = "stops.txt"
+	if !d.Next() {
+		t.Fatal(d.Err())
+	}
+
+	st := d.Row().(*Stop)
+	assert(t, st.Name == "Mission St.", "Wrong stop name")
+	assert(t, st.Latitude == "37.728631", "Wrong stop latitude")
+	assert(t, d.Extra()["tts_stop_name"] == "Mission Street", "Wrong extra tts_stop_name")
+	assert(t, d.Extra()["stop_access"] == "1", "Wrong extra stop_access")
+
+	w := d.Warnings()
+	if len(w) != 2 {
+		t.Fatalf("Wrong number of warnings %d", len(w))
+	}
+	assert(t, w[0].Column == "tts_stop_name" && w[0].Line == 1, "Wrong first warning")
+	assert(t, w[1].Column == "stop_access", "Wrong second warning")
+	assert(t, w[0].String() == "stops.txt:1: Ignoring unknown column tts_stop_name", "Wrong warning text "+w[0].String())
+}
+
+func TestDecodeExtraField(t *testing.T) {
+	type stopWithExtra struct {
+		Id    string            `gtfs_name:"stop_id" gtfs_required:"true"`
+		Extra map[string]string `gtfs_extra:"true"`
+	}
+
+	out, err := DecodeAs[stopWithExtra](strings.NewReader("stop_id,platform_code\nS1,2\nS2,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(out) == 2, "Wrong length of output")
+	assert(t, out[0].Extra["platform_code"] == "2", "Wrong extra value")
+	_, ok := out[1].Extra["platform_code"]
+	assert(t, ok, "Empty extra value should still be captured")
+}
+
+func TestDecodeMissingColumns(t *testing.T) {
+	s := `trip_id,arrival_time,departure_time
+AWE1,0:06:10,0:06:10`
+
+	d := NewDecoder(strings.NewReader(s), &StopTime{})
+	d.File = "stop_times.txt"
+	assert(t, !d.Next(), "Decoder should not return rows without required columns")
+
+	var mc *MissingColumnsError
+	if !errors.As(d.Err(), &mc) {
+		t.Fatalf("Wrong error %v", d.Err())
+	}
+
+	assert(t, mc.File == "stop_times.txt", "Wrong missing columns file")
+	assert(t, len(mc.Columns) == 2, "Wrong number of missing columns")
+	assert(t, mc.Columns[0] == "stop_id" && mc.Columns[1] == "stop_sequence", "Wrong missing columns")
+}
+
+func TestDecodeConditionalColumns(t *testing.T) {
+	s := `stop_id,location_type,parent_station
+N1,3,STN`
+
+	out, err := DecodeAs[Stop](strings.NewReader(s))
+	assert(t, err == nil && len(out) == 1, "Conditionally required columns should not be enforced by the decoder")
+}
+
+func TestDecodeErrorMissingValue(t *testing.T) {
+	s := `route_id,service_id,trip_id,trip_headsign
+A,WE,AWE1,Downtown
+A,,AWE2,Downtown`
+
+	d := NewDecoder(strings.NewReader(s), &Trip{})
+	d.File = "trips.txt"
+	for d.Next() {
+	}
+
+	var de *DecodeError
+	if !errors.As(d.Err(), &de) {
+		t.Fatalf("Wrong error %v", d.Err())
+	}
+
+	assert(t, de.File == "trips.txt", "Wrong decode error file")
+	assert(t, de.Line == 3, "Wrong decode error line")
+	assert(t, de.Column == 1, "Wrong decode error column")
+	assert(t, de.Field == "service_id", "Wrong decode error field")
+	assert(t, errors.Is(d.Err(), ErrMissingValue), "Decode error should wrap ErrMissingValue")
+	assert(t, de.Error() == "trips.txt:3: service_id: Missing required value", "Wrong decode error text "+de.Error())
+
+	_, err := DecodeAs[Trip](strings.NewReader("route_id,service_id,trip_id\nA,,AWE1"))
+	if err == nil {
+		t.Fatal("Missing value accepted")
+	}
+	assert(t, err.Error() == "line 2: service_id: Missing required value", "Wrong decode error text without a file "+err.Error())
+
+	d = NewDecoder(strings.NewReader(""), &Route{})
+	d.File = "routes.txt"
+	d.Next()
+	assert(t, d.Err() != nil && d.Err().Error() == "routes.txt: EOF", "Wrong error for an empty file")
+}
+
+func TestDecodeErrorMalformed(t *testing.T) {
+	s := `stop_id,stop_name,stop_lat,stop_lon
+S1,"Mission St.,37.728631,-122.431282`
+
+	d := NewDecoder(strings.NewReader(s), &Stop{})
+	for d.Next() {
+	}
+
+	var de *DecodeError
+	var pe *csv.ParseError
+	assert(t, errors.As(d.Err(), &de), "Malformed CSV should give a DecodeError")
+	assert(t, errors.As(d.Err(), &pe), "Malformed CSV should wrap a csv.ParseError")
+	assert(t, de.Line == 2, "Wrong decode error line")
+}
+
+func TestDecoderLenient(t *testing.T) {
+	s := `route_id,service_id,trip_id
+A,WE,AWE1
+A,,
+A,WE,AWE3
+,,AWE4
+A,WE,AWE5`
+
+	d := NewDecoder(strings.NewReader(s), &Trip{})
+	d.Lenient = true
+	ids := ""
+	for d.Next() {
+		ids += d.Row().(*Trip).Id
+	}
+
+	if d.Err() != nil {
+		t.Fatal(d.Err())
+	}
+
+	assert(t, ids == "AWE1AWE3AWE5", "Wrong rows returned "+ids)
+	assert(t, len(d.Errors()) == 4, "Wrong number of errors")
+	assert(t, d.ErrorCount() == 4, "Wrong error count")
+
+	var de *DecodeError
+	assert(t, errors.As(d.Errors()[1], &de) && de.Field == "trip_id" && de.Line == 3, "Wrong second error")
+}
+
+func TestDecoderLenientKeepInvalid(t *testing.T) {
+	s := `route_id,service_id,trip_id
+A,WE,AWE1
+A,,
+A,"WE,AWE3`
+
+	d := NewDecoder(strings.NewReader(s), &Trip{})
+	d.Lenient = true
+	d.KeepInvalid = true
+	d.MaxErrors = 1
+	count := 0
+	for d.Next() {
+		count++
+	}
+
+	if d.Err() != nil {
+		t.Fatal(d.Err())
+	}
+
+	assert(t, count == 2, "Invalid row should be kept")
+	assert(t, len(d.Errors()) == 1, "Errors should be capped")
+	assert(t, d.ErrorCount() == 3, "Wrong error count")
+}
+
+func TestDecodeByteOrderMark(t *testing.T) {
+	s := "\ufeff\"agency_id\", agency_name ,agency_url,agency_timezone\nFunBus,The Fun Bus,http://www.thefunbus.org,America/Los_Angeles"
+
+	out, err := DecodeAs[Agency](strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(out) == 1, "Wrong length of output")
+	assert(t, out[0].Id == "FunBus", "Wrong agency id")
+	assert(t, out[0].Name == "The Fun Bus", "Wrong agency name")
+}
+
+func TestDecodeWindows1252(t *testing.T) {
+	s := "stop_id,stop_name,stop_lat,stop_lon\nS1,Caf\xe9 \x93Central\x94 \x80,48.85,2.35"
+
+	d := NewDecoder(strings.NewReader(s), &Stop{})
+	d.Charset = Windows1252
+	if !d.Next() {
+		t.Fatal(d.Err())
+	}
+	assert(t, d.Row().(*Stop).Name == "Café “Central” €", "Wrong Windows-1252 stop name "+d.Row().(*Stop).Name)
+
+	d = NewDecoder(strings.NewReader(s), &Stop{})
+	d.Charset = Latin1
+	if !d.Next() {
+		t.Fatal(d.Err())
+	}
+	assert(t, d.Row().(*Stop).Name == "Café \u0093Central\u0094 \u0080", "Wrong Latin-1 stop name")
+}
+
+func TestDecodeRaggedRows(t *testing.T) {
+	s := `stop_id,stop_name,stop_lat,stop_lon,stop_desc,stop_url
+S1,Mission St.,37.728631,-122.431282,Corner,
+S2,Cortland Ave.,37.74103,-122.422482
+S3,24th St.,37.75223,-122.418581,,,,`
+
+	d := NewDecoder(strings.NewReader(s), &Stop{})
+	d.File = "stops.txt"
+	stops := make([]*Stop, 0)
+	for d.Next() {
+		stops = append(stops, d.Row().(*Stop))
+	}
+
+	if d.Err() != nil {
+		t.Fatal(d.Err())
+	}
+
+	assert(t, len(stops) == 3, "Wrong number of stops")
+	assert(t, stops[1].Longitude == "-122.422482", "Wrong short row longitude")
+	assert(t, stops[1].Description == "", "Wrong short row description")
+	assert(t, stops[2].Name == "24th St.", "Wrong long row name")
+
+	w := d.Warnings()
+	if len(w) != 2 {
+		t.Fatalf("Wrong number of warnings %d", len(w))
+	}
+	assert(t, w[0].Line == 3, "Wrong line for short row warning")
+	assert(t, w[1].Line == 4, "Wrong line for long row warning")
+}
+
+func TestDecodeRaggedRowsCounted(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("route_id,service_id,trip_id\n")
+	for i := 0; i < 1000; i++ {
+		b.WriteString("A,WE,T" + strconv.Itoa(i) + ",\n")
+	}
+	b.WriteString("A,WE\n")
+
+	d := NewDecoder(strings.NewReader(b.String()), &Trip{})
+	d.File = "trips.txt"
+	d.Lenient = true
+	n := 0
+	for d.Next() {
+		n++
+	}
+
+	assert(t, n == 1000, "Wrong number of trips")
+
+	w := d.Warnings()
+	if len(w) != 2 {
+		t.Fatalf("Wrong number of warnings %d", len(w))
+	}
+	assert(t, w[0].Line == 2 && w[0].Count == 1000, "Wrong long row warning")
+	assert(t, len(w[0].Lines) == 1000 && w[0].Lines[999] == 1001, "Wrong lines for long row warning")
+	assert(t, w[0].String() == "trips.txt:2: Ignoring fields past the end of the header (1000 rows)", "Wrong warning text "+w[0].String())
+	assert(t, w[1].Line == 1002 && w[1].Count == 1, "Wrong short row warning")
+}
+
+func TestDecodeShortRowMissingRequired(t *testing.T) {
+	s := `route_id,service_id,trip_id
+A,WE`
+
+	d := NewDecoder(strings.NewReader(s), &Trip{})
+	assert(t, !d.Next(), "Short row without required field should fail")
+
+	var de *DecodeError
+	assert(t, errors.As(d.Err(), &de) && de.Field == "trip_id" && de.Line == 2, "Wrong error for short row")
+}
+
+type typedStop struct {
+	Id           string  `gtfs_name:"stop_id" gtfs_required:"true"`
+	Latitude     float64 `gtfs_name:"stop_lat" gtfs_required:"true"`
+	Longitude    float64 `gtfs_name:"stop_lon" gtfs_required:"true"`
+	LocationType int     `gtfs_name:"location_type" gtfs_required:"false"`
+}
+
+type typedStopTime struct {
+	TripId       string      `gtfs_name:"trip_id" gtfs_required:"true"`
+	ArrivalTime  ServiceTime `gtfs_name:"arrival_time" gtfs_required:"false"`
+	StopSequence uint32      `gtfs_name:"stop_sequence" gtfs_required:"true"`
+	TimePoint    bool        `gtfs_name:"timepoint" gtfs_required:"false"`
+}
+
+type typedRoute struct {
+	Id    string `gtfs_name:"route_id" gtfs_required:"true"`
+	Type  int8   `gtfs_name:"route_type" gtfs_required:"true"`
+	Color Color  `gtfs_name:"route_color" gtfs_required:"false"`
+}
+
+func TestDecodeTypedFields(t *testing.T) {
+	stops, err := DecodeAs[typedStop](strings.NewReader("stop_id,stop_lat,stop_lon,location_type\nS1,37.728631,-122.431282,1\nS2,37.74103,-122.422482,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, stops[0].Latitude == 37.728631 && stops[0].Longitude == -122.431282, "Wrong typed coordinates")
+	assert(t, stops[0].LocationType == 1 && stops[1].LocationType == 0, "Wrong typed location type")
+
+	times, err := DecodeAs[typedStopTime](strings.NewReader("trip_id,arrival_time,stop_sequence,timepoint\nAWE1,25:35:00,3,1\nAWE1,,4,0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, times[0].ArrivalTime == 25*3600+35*60 && times[1].ArrivalTime == 0, "Wrong typed arrival time")
+	assert(t, times[0].StopSequence == 3, "Wrong typed stop sequence")
+	assert(t, times[0].TimePoint && !times[1].TimePoint, "Wrong typed timepoint")
+
+	routes, err := DecodeAs[typedRoute](strings.NewReader("route_id,route_type,route_color\nA,3,FF0000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, routes[0].Type == 3 && routes[0].Color == Color{255, 0, 0}, "Wrong typed route")
+}
+
+func TestDecodeTypedFieldErrors(t *testing.T) {
+	s := `stop_id,stop_lat,stop_lon
+S1,north,-122.431282`
+
+	d := NewDecoder(strings.NewReader(s), &typedStop{})
+	d.File = "stops.txt"
+	assert(t, !d.Next(), "Invalid float accepted")
+
+	var de *DecodeError
+	if !errors.As(d.Err(), &de) {
+		t.Fatalf("Wrong error %v", d.Err())
+	}
+	assert(t, de.Field == "stop_lat" && de.Column == 1 && de.Value == "north", "Wrong decode error for invalid float")
+
+	_, err := DecodeAs[typedRoute](strings.NewReader("route_id,route_type\nA,300"))
+	assert(t, err != nil, "Out of range int8 accepted")
+
+	type unsupported struct {
+		Id []string `gtfs_name:"stop_id"`
+	}
+	_, err = DecodeAs[unsupported](strings.NewReader("stop_id\nS1"))
+	assert(t, err != nil, "Unsupported field type accepted")
+}
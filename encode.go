@@ -0,0 +1,321 @@
+package gtfs
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+// Formats v the way setField would parse it, with nil pointers as empty
+func formatField(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if z, ok := v.Interface().(isZeroer); ok && z.IsZero() {
+		return "", nil
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+
+	return "", errors.New("Unsupported field type " + v.Type().String())
+}
+
+// Writes rows of a struct type with gtfs_name tags as a GTFS CSV file. The
+// header lists the tagged fields in struct order.
+//
+//	e := NewEncoder(w, &Stop{})
+//	for _, s := range stops {
+//		e.Encode(s)
+//	}
+//	err := e.Flush()
+//
+// Fields tagged gtfs_extra are not written.
+type Encoder struct {
+	// Leave out optional columns which are empty in every row. The rows are
+	// held until the first Flush so that the header can be decided, and rows
+	// encoded after it with a value in a left out column are an error. Held
+	// rows are formatted, and any error returned, by that Flush.
+	OmitEmpty bool
+
+	w       *csv.Writer
+	t       reflect.Type
+	fields  []int
+	keep    []bool
+	rows    []interface{}
+	started bool
+	err     error
+}
+
+// Creates an encoder for rows of the same type as rowtype, which must be a
+// pointer to a struct with gtfs_name tags
+func NewEncoder(w io.Writer, rowtype interface{}) *Encoder {
+	e := &Encoder{w: csv.NewWriter(w)}
+
+	t := reflect.TypeOf(rowtype)
+	if t == nil || t.Kind() != reflect.Ptr {
+		e.err = errors.New("Row type must be a pointer to a struct")
+		return e
+	}
+
+	e.t = t.Elem()
+	e.err = checkRowType(e.t)
+	if e.err != nil {
+		return e
+	}
+
+	for i := 0; i < e.t.NumField(); i++ {
+		if e.t.Field(i).Tag.Get("gtfs_name") != "" {
+			e.fields = append(e.fields, i)
+		}
+	}
+
+	return e
+}
+
+// The struct value of row, checking it is of the encoder's row type
+func (e *Encoder) rowValue(row interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(row)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return v, errors.New("Row is nil")
+	}
+
+	if v.Type() != e.t {
+		return v, errors.New("Row is a " + v.Type().String() + " not a " + e.t.String())
+	}
+
+	return v, nil
+}
+
+// Writes one row, a struct or pointer to a struct of the encoder's row type
+func (e *Encoder) Encode(row interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	v, err := e.rowValue(row)
+	if err != nil {
+		e.err = err
+		return e.err
+	}
+
+	if e.OmitEmpty && !e.started {
+		// Copied so that later changes to row do not change what is written
+		c := reflect.New(e.t)
+		c.Elem().Set(v)
+		e.rows = append(e.rows, c.Interface())
+		return nil
+	}
+
+	record, err := e.record(v)
+	if err != nil {
+		e.err = err
+		return e.err
+	}
+
+	for i, v := range record {
+		if v != "" && e.keep != nil && !e.keep[i] {
+			e.err = errors.New("Column " + e.t.Field(e.fields[i]).Tag.Get("gtfs_name") + " was left out but has a value")
+			return e.err
+		}
+	}
+
+	if !e.started {
+		e.started = true
+		e.err = e.w.Write(e.compact(e.header()))
+		if e.err != nil {
+			return e.err
+		}
+	}
+
+	e.err = e.w.Write(e.compact(record))
+	return e.err
+}
+
+// The formatted values of every tagged field of v
+func (e *Encoder) record(v reflect.Value) ([]string, error) {
+	record := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		var err error
+		record[i], err = formatField(v.Field(f))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+func (e *Encoder) header() []string {
+	header := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		header[i] = e.t.Field(f).Tag.Get("gtfs_name")
+	}
+
+	return header
+}
+
+// The values of record in the columns being kept
+func (e *Encoder) compact(record []string) []string {
+	if e.keep == nil {
+		return record
+	}
+
+	out := make([]string, 0, len(record))
+	for i, v := range record {
+		if e.keep[i] {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// Decides the columns to write from every row: the required ones and any with
+// a value. OmitEmpty uses it on the held rows, and calling it up front lets
+// rows be passed to Encode one at a time without being held until Flush. row
+// returns the i'th of n rows.
+func (e *Encoder) keepUsedColumns(n int, row func(i int) interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	keep := make([]bool, len(e.fields))
+	for i, f := range e.fields {
+		keep[i] = e.t.Field(f).Tag.Get("gtfs_required") == "true"
+	}
+
+	for r := 0; r < n; r++ {
+		v, err := e.rowValue(row(r))
+		if err != nil {
+			e.err = err
+			return e.err
+		}
+
+		for i, f := range e.fields {
+			if keep[i] {
+				continue
+			}
+
+			s, err := formatField(v.Field(f))
+			if err != nil {
+				e.err = err
+				return e.err
+			}
+			keep[i] = s != ""
+		}
+	}
+
+	e.keep = keep
+	return nil
+}
+
+// Writes out anything buffered, including the header if no rows were written
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if e.OmitEmpty && !e.started {
+		e.err = e.writeCompact()
+	} else if !e.started {
+		e.started = true
+		e.err = e.w.Write(e.compact(e.header()))
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+
+	e.w.Flush()
+	e.err = e.w.Error()
+	return e.err
+}
+
+// Decides the columns from the rows held so far and writes them out
+func (e *Encoder) writeCompact() error {
+	if err := e.keepUsedColumns(len(e.rows), func(i int) interface{} { return e.rows[i] }); err != nil {
+		return err
+	}
+
+	e.started = true
+	if err := e.w.Write(e.compact(e.header())); err != nil {
+		return err
+	}
+
+	for _, r := range e.rows {
+		record, err := e.record(reflect.ValueOf(r).Elem())
+		if err != nil {
+			return err
+		}
+
+		if err := e.w.Write(e.compact(record)); err != nil {
+			return err
+		}
+	}
+	e.rows = nil
+
+	return nil
+}
+
+// Writes rows, a slice of tagged structs or pointers to them, as a GTFS CSV
+// file with every tagged column
+func Encode(w io.Writer, rows interface{}) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return errors.New("Rows must be a slice")
+	}
+
+	t := v.Type().Elem()
+	if t.Kind() == reflect.Interface {
+		if v.Len() == 0 || v.Index(0).IsNil() {
+			return errors.New("Cannot tell the row type from the first row")
+		}
+		t = v.Index(0).Elem().Type()
+	}
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PointerTo(t)
+	}
+
+	e := NewEncoder(w, reflect.Zero(t).Interface())
+	for i := 0; i < v.Len(); i++ {
+		if err := e.Encode(v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+
+	return e.Flush()
+}
@@ -0,0 +1,165 @@
+package gtfs
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	stops := []*Stop{
+		{Id: "S1", Name: "Mission St. & Silver Ave.", Latitude: "37.728631", Longitude: "-122.431282"},
+		{Id: "S2", Name: `The "Mission", at 24th`, Latitude: "37.74103", Longitude: "-122.422482", LocationType: "1"},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, stops); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	assert(t, lines[0] == "stop_id,stop_code,stop_name,stop_desc,stop_lat,stop_lon,zone_id,stop_url,location_type,parent_station,stop_timezone,wheelchair_boarding,level_id,platform_code", "Wrong header "+lines[0])
+	assert(t, lines[2] == `S2,,"The ""Mission"", at 24th",,37.74103,-122.422482,,,1,,,,,`, "Wrong quoting "+lines[2])
+}
+
+func TestEncoderOmitEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, &Transfer{})
+	e.OmitEmpty = true
+	e.Encode(&Transfer{FromStopId: "S6", ToStopId: "S7", TransferType: "2"})
+	e.Encode(Transfer{FromStopId: "S7", ToStopId: "S6", TransferType: "3"})
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, buf.String() == "from_stop_id,to_stop_id,transfer_type\nS6,S7,2\nS7,S6,3\n", "Wrong compact output "+buf.String())
+
+	err := e.Encode(&Stop{})
+	assert(t, err != nil, "Row of the wrong type accepted")
+}
+
+func TestEncoderOmitEmptyAfterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, &Stop{})
+	e.OmitEmpty = true
+	e.Encode(&Stop{Id: "S1", Name: "Mission St."})
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := e.Encode(&Stop{Id: "S2", Name: "Cortland Ave."})
+	assert(t, err == nil, "Row without new values rejected")
+
+	err = e.Encode(&Stop{Id: "S3", Code: "XYZ"})
+	assert(t, err != nil && strings.Contains(err.Error(), "stop_code"), "Value in a left out column accepted")
+	assert(t, e.Flush() != nil, "Flush should report the lost value")
+
+	assert(t, buf.String() == "stop_id,stop_name\nS1,Mission St.\n", "Wrong output "+buf.String())
+}
+
+func TestEncodeTypedFields(t *testing.T) {
+	var buf bytes.Buffer
+	routes := []typedRoute{{Id: "A", Type: 3, Color: Color{255, 0, 0}}}
+	if err := Encode(&buf, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, buf.String() == "route_id,route_type,route_color\nA,3,FF0000\n", "Wrong typed output "+buf.String())
+}
+
+func roundTrip(t *testing.T, s string, rowtype interface{}) {
+	out, err := Decode(strings.NewReader(s), rowtype)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, rowtype)
+	e.OmitEmpty = true
+	for _, r := range out {
+		if err := e.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := Decode(&buf, rowtype)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(out, again) {
+		t.Errorf("Round trip of %T changed the records", rowtype)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	roundTrip(t, `agency_id,agency_name,agency_url,agency_timezone,agency_phone,agency_lang
+FunBus,The Fun Bus,http://www.thefunbus.org,America/Los_Angeles,(310) 555-0222,en`, &Agency{})
+
+	roundTrip(t, `route_id,route_short_name,route_long_name,route_desc,route_type
+A,17,Mission,"The ""A"" route travels from lower Mission to Downtown.",3`, &Route{})
+
+	roundTrip(t, `stop_id,stop_name,stop_desc,stop_lat,stop_lon,stop_url,location_type,parent_station
+S7,24th St. Mission Station,,37.752240,-122.418450,,,S8
+S8,24th St. Mission Station,,37.752240,-122.418450,http://www.bart.gov/stations/stationguide/stationoverview_24st.asp,1,`, &Stop{})
+
+	roundTrip(t, `fare_id,route_id,origin_id,destination_id,contains_id
+a,TSW,1,1,
+c,GRT,,,6`, &FareRule{})
+}
+
+type optionalFields struct {
+	Id    string       `gtfs_name:"id" gtfs_required:"true"`
+	Color *Color       `gtfs_name:"c" gtfs_required:"false"`
+	Time  *ServiceTime `gtfs_name:"t" gtfs_required:"false"`
+	Count *int         `gtfs_name:"n" gtfs_required:"false"`
+}
+
+func TestEncodeEmptyTypedFieldsRoundTrip(t *testing.T) {
+	s := "id,c,t,n\nA,,,\nB,000000,00:00:00,0\n"
+
+	rows, err := DecodeAs[optionalFields](strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, rows[0].Color == nil && rows[0].Time == nil && rows[0].Count == nil, "Empty values should be nil")
+	assert(t, *rows[1].Color == Color{} && *rows[1].Time == 0 && *rows[1].Count == 0, "Zero values should be set")
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, buf.String() == s, "Round trip changed the values "+buf.String())
+}
+
+func TestEncoderKeepUsedColumns(t *testing.T) {
+	transfers := []*Transfer{
+		{FromStopId: "S6", ToStopId: "S7", TransferType: "2"},
+		{FromStopId: "S7", ToStopId: "S6", TransferType: "2", MinimumTransferTime: "180"},
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, &Transfer{})
+	if err := e.keepUsedColumns(1, func(i int) interface{} { return transfers[i] }); err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, e.Encode(transfers[0]) == nil, "Row with only kept columns rejected")
+	assert(t, len(e.rows) == 0, "Rows should be streamed, not held")
+	assert(t, e.Encode(transfers[1]) != nil, "Value in a left out column accepted")
+
+	buf.Reset()
+	e = NewEncoder(&buf, &Transfer{})
+	e.keepUsedColumns(len(transfers), func(i int) interface{} { return transfers[i] })
+	for _, tr := range transfers {
+		e.Encode(tr)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, buf.String() == "from_stop_id,to_stop_id,transfer_type,min_transfer_time\nS6,S7,2,\nS7,S6,2,180\n", "Wrong output "+buf.String())
+}
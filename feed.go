@@ -0,0 +1,293 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A complete GTFS feed, one slice per file. Lookup methods such as Stop and
+// TripsForRoute build their indexes on first use, so call Reindex after
+// changing the slices.
+type Feed struct {
+	Agencies          []*Agency
+	Stops             []*Stop
+	Routes            []*Route
+	Trips             []*Trip
+	StopTimes         []*StopTime
+	Services          []*Service
+	ServiceExceptions []*ServiceException
+	Fares             []*Fare
+	FareRules         []*FareRule
+	ShapePoints       []*ShapePoint
+	Frequencies       []*Frequency
+	Transfers         []*Transfer
+	FeedInfo          []*FeedInfo
+	Pathways          []*Pathway
+	Levels            []*Level
+	Translations      []*Translation
+	Attributions      []*Attribution
+	FareMedia         []*FareMedia
+	FareProducts      []*FareProduct
+	FareLegRules      []*FareLegRule
+	FareTransferRules []*FareTransferRule
+	RiderCategories   []*RiderCategory
+	Timeframes        []*Timeframe
+	Areas             []*Area
+	StopAreas         []*StopArea
+	Networks          []*Network
+	RouteNetworks     []*RouteNetwork
+
+	// Anything ignored while loading, such as unknown columns
+	Warnings []Warning
+
+	indexLock sync.Mutex
+	index     *feedIndex
+}
+
+type feedFile struct {
+	name     string
+	required bool
+	load     func(f *Feed, r io.Reader) ([]Warning, error)
+	save     func(f *Feed, w io.Writer) error
+	count    func(f *Feed) int
+	each     func(f *Feed, fn func(i int, row interface{}))
+}
+
+// Describes a file whose rows are stored in the slice returned by rows
+func newFeedFile[T any](name string, required bool, rows func(f *Feed) *[]*T) feedFile {
+	return feedFile{
+		name:     name,
+		required: required,
+		load: func(f *Feed, r io.Reader) ([]Warning, error) {
+			d := NewDecoder(r, new(T))
+			d.File = name
+
+			out, err := decodeAll[T](d)
+			*rows(f) = out
+			return d.Warnings(), err
+		},
+		save: func(f *Feed, w io.Writer) error {
+			// The rows are already in memory, so find the used columns first
+			// and stream them rather than have OmitEmpty hold a copy
+			e := NewEncoder(w, new(T))
+			rs := *rows(f)
+			if err := e.keepUsedColumns(len(rs), func(i int) interface{} { return rs[i] }); err != nil {
+				return err
+			}
+
+			for _, r := range rs {
+				if err := e.Encode(r); err != nil {
+					return err
+				}
+			}
+
+			return e.Flush()
+		},
+		count: func(f *Feed) int {
+			return len(*rows(f))
+		},
+		each: func(f *Feed, fn func(i int, row interface{})) {
+			for i, r := range *rows(f) {
+				fn(i, r)
+			}
+		},
+	}
+}
+
+var feedFiles = []feedFile{
+	newFeedFile("agency.txt", true, func(f *Feed) *[]*Agency { return &f.Agencies }),
+	newFeedFile("stops.txt", true, func(f *Feed) *[]*Stop { return &f.Stops }),
+	newFeedFile("routes.txt", true, func(f *Feed) *[]*Route { return &f.Routes }),
+	newFeedFile("trips.txt", true, func(f *Feed) *[]*Trip { return &f.Trips }),
+	newFeedFile("stop_times.txt", true, func(f *Feed) *[]*StopTime { return &f.StopTimes }),
+	newFeedFile("calendar.txt", false, func(f *Feed) *[]*Service { return &f.Services }),
+	newFeedFile("calendar_dates.txt", false, func(f *Feed) *[]*ServiceException { return &f.ServiceExceptions }),
+	newFeedFile("fare_attributes.txt", false, func(f *Feed) *[]*Fare { return &f.Fares }),
+	newFeedFile("fare_rules.txt", false, func(f *Feed) *[]*FareRule { return &f.FareRules }),
+	newFeedFile("shapes.txt", false, func(f *Feed) *[]*ShapePoint { return &f.ShapePoints }),
+	newFeedFile("frequencies.txt", false, func(f *Feed) *[]*Frequency { return &f.Frequencies }),
+	newFeedFile("transfers.txt", false, func(f *Feed) *[]*Transfer { return &f.Transfers }),
+	newFeedFile("feed_info.txt", false, func(f *Feed) *[]*FeedInfo { return &f.FeedInfo }),
+	newFeedFile("pathways.txt", false, func(f *Feed) *[]*Pathway { return &f.Pathways }),
+	newFeedFile("levels.txt", false, func(f *Feed) *[]*Level { return &f.Levels }),
+	newFeedFile("translations.txt", false, func(f *Feed) *[]*Translation { return &f.Translations }),
+	newFeedFile("attributions.txt", false, func(f *Feed) *[]*Attribution { return &f.Attributions }),
+	newFeedFile("fare_media.txt", false, func(f *Feed) *[]*FareMedia { return &f.FareMedia }),
+	newFeedFile("fare_products.txt", false, func(f *Feed) *[]*FareProduct { return &f.FareProducts }),
+	newFeedFile("fare_leg_rules.txt", false, func(f *Feed) *[]*FareLegRule { return &f.FareLegRules }),
+	newFeedFile("fare_transfer_rules.txt", false, func(f *Feed) *[]*FareTransferRule { return &f.FareTransferRules }),
+	newFeedFile("rider_categories.txt", false, func(f *Feed) *[]*RiderCategory { return &f.RiderCategories }),
+	newFeedFile("timeframes.txt", false, func(f *Feed) *[]*Timeframe { return &f.Timeframes }),
+	newFeedFile("areas.txt", false, func(f *Feed) *[]*Area { return &f.Areas }),
+	newFeedFile("stop_areas.txt", false, func(f *Feed) *[]*StopArea { return &f.StopAreas }),
+	newFeedFile("networks.txt", false, func(f *Feed) *[]*Network { return &f.Networks }),
+	newFeedFile("route_networks.txt", false, func(f *Feed) *[]*RouteNetwork { return &f.RouteNetworks }),
+}
+
+// Loads a feed from a zip archive of size bytes
+func LoadZip(r io.ReaderAt, size int64) (*Feed, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFS(z)
+}
+
+// Loads a feed from the zip archive or directory at path
+func LoadFeed(path string) (*Feed, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return LoadDir(path)
+	}
+
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	return LoadFS(z)
+}
+
+// Loads a feed from an unpacked directory
+func LoadDir(path string) (*Feed, error) {
+	return LoadFS(os.DirFS(path))
+}
+
+// Loads a feed from the files at the root of fsys. Optional files which are
+// not present or are completely empty are left empty.
+func LoadFS(fsys fs.FS) (*Feed, error) {
+	missing := make([]string, 0)
+	for _, ff := range feedFiles {
+		if !ff.required {
+			continue
+		}
+
+		_, err := fs.Stat(fsys, ff.name)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, ff.name)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("Feed is missing required files " + strings.Join(missing, ", "))
+	}
+
+	feed := &Feed{}
+	for _, ff := range feedFiles {
+		file, err := fsys.Open(ff.name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		warnings, err := ff.load(feed, file)
+		file.Close()
+		feed.Warnings = append(feed.Warnings, warnings...)
+
+		// Exports often include optional files with not even a header
+		if errors.Is(err, io.EOF) {
+			if !ff.required {
+				continue
+			}
+			return nil, errors.New(ff.name + " is empty")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return feed, nil
+}
+
+// The modification time of every file in zips written by WriteZip, so that
+// the same feed always produces the same bytes
+var zipTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Writes the feed as a zip archive. Files are written in a fixed order with
+// fixed timestamps, optional files with no rows are skipped, and optional
+// columns which are empty in every row are left out.
+func (f *Feed) WriteZip(w io.Writer) error {
+	z := zip.NewWriter(w)
+	for _, ff := range feedFiles {
+		if !ff.required && ff.count(f) == 0 {
+			continue
+		}
+
+		fw, err := z.CreateHeader(&zip.FileHeader{
+			Name:     ff.name,
+			Method:   zip.Deflate,
+			Modified: zipTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := ff.save(f, fw); err != nil {
+			return fmt.Errorf("%s: %w", ff.name, err)
+		}
+	}
+
+	return z.Close()
+}
+
+// Writes the feed as a zip archive at path
+func (f *Feed) SaveZip(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = f.WriteZip(file)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Writes the feed's files into the directory at path, creating it if needed.
+// As with WriteZip, optional files with no rows are skipped.
+func (f *Feed) WriteDir(path string) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	for _, ff := range feedFiles {
+		if !ff.required && ff.count(f) == 0 {
+			continue
+		}
+
+		file, err := os.Create(filepath.Join(path, ff.name))
+		if err != nil {
+			return err
+		}
+
+		err = ff.save(f, file)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", ff.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testFeedFiles = map[string]string{
+	"agency.txt": `agency_id,agency_name,agency_url,agency_timezone
+FunBus,The Fun Bus,http://www.thefunbus.org,America/Los_Angeles`,
+	"stops.txt": `stop_id,stop_name,stop_lat,stop_lon,tts_stop_name
+S1,Mission St. & Silver Ave.,37.728631,-122.431282,
+S2,Mission St. & Cortland Ave.,37.74103,-122.422482,`,
+	"routes.txt": `route_id,route_short_name,route_long_name,route_type
+A,17,Mission,3`,
+	"trips.txt": `route_id,service_id,trip_id
+A,WE,AWE1`,
+	"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+AWE1,0:06:10,0:06:10,S1,1
+AWE1,0:06:20,0:06:20,S2,2`,
+	"calendar.txt": `service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date
+WE,0,0,0,0,0,1,1,20060701,20060731`,
+	"feed_info.txt": `feed_publisher_name,feed_publisher_url,feed_lang,feed_start_date,feed_end_date,feed_version
+The Fun Bus,http://www.thefunbus.org,en,20060701,20060731,2006.1`,
+}
+
+// Loads testFeedFiles with the files in overrides added or replacing theirs
+func loadTestFeed(t *testing.T, overrides map[string]string) *Feed {
+	files := make(map[string]string)
+	for name, s := range testFeedFiles {
+		files[name] = s
+	}
+	for name, s := range overrides {
+		files[name] = s
+	}
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return feed
+}
+
+func makeZip(t *testing.T, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(files[name]))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestLoadZip(t *testing.T) {
+	b := makeZip(t, testFeedFiles)
+
+	feed, err := LoadZip(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(feed.Agencies) == 1, "Wrong number of agencies")
+	assert(t, len(feed.Stops) == 2, "Wrong number of stops")
+	assert(t, len(feed.Routes) == 1, "Wrong number of routes")
+	assert(t, len(feed.Trips) == 1, "Wrong number of trips")
+	assert(t, len(feed.StopTimes) == 2, "Wrong number of stop times")
+	assert(t, len(feed.Services) == 1, "Wrong number of services")
+	assert(t, len(feed.ServiceExceptions) == 0, "Wrong number of service exceptions")
+	assert(t, len(feed.FeedInfo) == 1, "Wrong number of feed info rows")
+
+	assert(t, feed.Agencies[0].Name == "The Fun Bus", "Wrong agency name")
+	assert(t, feed.StopTimes[1].StopId == "S2", "Wrong stop time stop id")
+	assert(t, feed.FeedInfo[0].Version == "2006.1", "Wrong feed version")
+}
+
+func TestLoadZipMissingFiles(t *testing.T) {
+	files := map[string]string{
+		"agency.txt": testFeedFiles["agency.txt"],
+		"routes.txt": testFeedFiles["routes.txt"],
+	}
+	b := makeZip(t, files)
+
+	_, err := LoadZip(bytes.NewReader(b), int64(len(b)))
+	if err == nil {
+		t.Fatal("Expected error for missing files")
+	}
+
+	assert(t, strings.Contains(err.Error(), "stops.txt"), "Missing stops.txt not reported")
+	assert(t, strings.Contains(err.Error(), "trips.txt"), "Missing trips.txt not reported")
+	assert(t, strings.Contains(err.Error(), "stop_times.txt"), "Missing stop_times.txt not reported")
+	assert(t, !strings.Contains(err.Error(), "agency.txt"), "Present agency.txt reported missing")
+}
+
+func makeMapFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, contents := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(contents)}
+	}
+
+	return fsys
+}
+
+func TestLoadFS(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(testFeedFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(feed.Stops) == 2, "Wrong number of stops")
+	assert(t, len(feed.Services) == 1, "Wrong number of services")
+	assert(t, feed.Fares == nil, "Absent fares should be empty")
+	assert(t, feed.Transfers == nil, "Absent transfers should be empty")
+
+	assert(t, len(feed.Warnings) == 1, "Wrong number of warnings")
+	assert(t, feed.Warnings[0].File == "stops.txt", "Wrong warning file")
+	assert(t, feed.Warnings[0].Column == "tts_stop_name", "Wrong warning column")
+}
+
+func TestLoadFSEmptyFiles(t *testing.T) {
+	files := make(map[string]string)
+	for name, s := range testFeedFiles {
+		files[name] = s
+	}
+	files["calendar_dates.txt"] = ""
+	files["transfers.txt"] = "\ufeff"
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, feed.ServiceExceptions == nil, "Empty calendar_dates.txt should have no rows")
+	assert(t, feed.Transfers == nil, "Empty transfers.txt should have no rows")
+
+	files["routes.txt"] = ""
+	_, err = LoadFS(makeMapFS(files))
+	if err == nil {
+		t.Fatal("Empty required file accepted")
+	}
+	assert(t, err.Error() == "routes.txt is empty", "Wrong error for empty required file "+err.Error())
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range testFeedFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	feed, err := LoadFeed(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(feed.Agencies) == 1, "Wrong number of agencies")
+	assert(t, feed.Routes[0].LongName == "Mission", "Wrong route long name")
+}
+
+func TestWriteZip(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(testFeedFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first, second bytes.Buffer
+	if err := feed.WriteZip(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := feed.WriteZip(&second); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, bytes.Equal(first.Bytes(), second.Bytes()), "Zip output is not deterministic")
+
+	z, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0)
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+	assert(t, strings.Join(names, ",") == "agency.txt,stops.txt,routes.txt,trips.txt,stop_times.txt,calendar.txt,feed_info.txt", "Wrong zip files "+strings.Join(names, ","))
+
+	again, err := LoadZip(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Warnings = feed.Warnings
+	assert(t, reflect.DeepEqual(feed, again), "Feed changed after writing and loading")
+}
+
+func TestWriteDir(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(testFeedFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Agencies[0].Name = `The "Fun" Bus, Inc.`
+
+	dir := filepath.Join(t.TempDir(), "feed")
+	if err := feed.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "transfers.txt"))
+	assert(t, os.IsNotExist(err), "Empty optional file should not be written")
+
+	again, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, again.Agencies[0].Name == `The "Fun" Bus, Inc.`, "Wrong agency name after writing")
+	assert(t, len(again.StopTimes) == 2, "Wrong number of stop times after writing")
+}
+
+var testFaresFiles = map[string]string{
+	"fare_media.txt": `fare_media_id,fare_media_name,fare_media_type
+CARD,Fun Card,2
+APP,Fun App,4`,
+	"rider_categories.txt": `rider_category_id,rider_category_name,is_default_fare_category,eligibility_url
+ADULT,Adult,1,
+YOUTH,Youth,0,http://www.thefunbus.org/youth`,
+	"fare_products.txt": `fare_product_id,fare_product_name,rider_category_id,fare_media_id,amount,currency
+SINGLE,Single ride,ADULT,CARD,2.50,USD
+SINGLE,Single ride,YOUTH,CARD,1.25,USD
+XFER,Transfer,,APP,0.50,USD`,
+	"areas.txt": `area_id,area_name
+NORTH,North
+SOUTH,South`,
+	"stop_areas.txt": `area_id,stop_id
+SOUTH,S1
+NORTH,S2`,
+	"networks.txt": `network_id,network_name
+LOCAL,Local buses`,
+	"route_networks.txt": `network_id,route_id
+LOCAL,A`,
+	"timeframes.txt": `timeframe_group_id,start_time,end_time,service_id
+PEAK,07:00:00,09:30:00,WE
+ANY,,,WE`,
+	"fare_leg_rules.txt": `leg_group_id,network_id,from_area_id,to_area_id,from_timeframe_group_id,to_timeframe_group_id,fare_product_id,rule_priority
+LOCAL_LEG,LOCAL,SOUTH,NORTH,PEAK,,SINGLE,1
+LOCAL_LEG,LOCAL,,,,,SINGLE,0`,
+	"fare_transfer_rules.txt": `from_leg_group_id,to_leg_group_id,transfer_count,duration_limit,duration_limit_type,fare_transfer_type,fare_product_id
+LOCAL_LEG,LOCAL_LEG,2,5400,1,1,XFER`,
+}
+
+func withFares(files map[string]string) map[string]string {
+	out := make(map[string]string)
+	for name, s := range files {
+		out[name] = s
+	}
+	for name, s := range testFaresFiles {
+		out[name] = s
+	}
+
+	return out
+}
+
+func TestLoadFaresV2(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(withFares(testFeedFiles)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(feed.FareMedia) == 2, "Wrong number of fare media")
+	assert(t, len(feed.RiderCategories) == 2, "Wrong number of rider categories")
+	assert(t, len(feed.FareProducts) == 3, "Wrong number of fare products")
+	assert(t, len(feed.Areas) == 2, "Wrong number of areas")
+	assert(t, len(feed.StopAreas) == 2, "Wrong number of stop areas")
+	assert(t, len(feed.Networks) == 1, "Wrong number of networks")
+	assert(t, len(feed.RouteNetworks) == 1, "Wrong number of route networks")
+	assert(t, len(feed.Timeframes) == 2, "Wrong number of timeframes")
+	assert(t, len(feed.FareLegRules) == 2, "Wrong number of fare leg rules")
+	assert(t, len(feed.FareTransferRules) == 1, "Wrong number of fare transfer rules")
+
+	assert(t, feed.FareProducts[1].Amount == "1.25", "Wrong fare product amount")
+	assert(t, feed.FareLegRules[0].FromTimeframeGroupId == "PEAK", "Wrong leg rule timeframe")
+	assert(t, feed.FareTransferRules[0].DurationLimit == "5400", "Wrong transfer duration limit")
+
+	var buf bytes.Buffer
+	if err := feed.WriteZip(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := LoadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Warnings = feed.Warnings
+	assert(t, reflect.DeepEqual(feed, again), "Fares changed after writing and loading")
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Error(f.String())
+	}
+}
@@ -1,11 +1,6 @@
 package gtfs
 
-import (
-	"encoding/csv"
-	"errors"
-	"io"
-	"reflect"
-)
+import "time"
 
 // From agency.txt
 type Agency struct {
@@ -23,20 +18,27 @@ func (a *Agency) String() string {
 	return a.Name + " " + a.Url
 }
 
+// The location of the agency's timezone
+func (a *Agency) Location() (*time.Location, error) {
+	return time.LoadLocation(a.Timezone)
+}
+
 // From stops.txt
 type Stop struct {
 	Id                 string `gtfs_name:"stop_id" gtfs_required:"true"`
 	Code               string `gtfs_name:"stop_code" gtfs_required:"false"`
-	Name               string `gtfs_name:"stop_name" gtfs_required:"true"`
+	Name               string `gtfs_name:"stop_name" gtfs_required:"conditional"`
 	Description        string `gtfs_name:"stop_desc" gtfs_required:"false"`
-	Latitude           string `gtfs_name:"stop_lat" gtfs_required:"true"`
-	Longitude          string `gtfs_name:"stop_lon" gtfs_required:"true"`
+	Latitude           string `gtfs_name:"stop_lat" gtfs_required:"conditional"`
+	Longitude          string `gtfs_name:"stop_lon" gtfs_required:"conditional"`
 	ZoneId             string `gtfs_name:"zone_id" gtfs_required:"false"`
 	Url                string `gtfs_name:"stop_url" gtfs_required:"false"`
 	LocationType       string `gtfs_name:"location_type" gtfs_required:"false"`
 	ParentStation      string `gtfs_name:"parent_station" gtfs_required:"false"`
 	Timezone           string `gtfs_name:"stop_timezone" gtfs_required:"false"`
 	WheelchairBoarding string `gtfs_name:"wheelchair_boarding" gtfs_required:"false"`
+	LevelId            string `gtfs_name:"level_id" gtfs_required:"false"`
+	PlatformCode       string `gtfs_name:"platform_code" gtfs_required:"false"`
 }
 
 func (s *Stop) String() string {
@@ -47,14 +49,15 @@ func (s *Stop) String() string {
 type Route struct {
 	Id          string `gtfs_name:"route_id" gtfs_required:"true"`
 	AgencyId    string `gtfs_name:"agency_id" gtfs_required:"false"`
-	ShortName   string `gtfs_name:"route_short_name" gtfs_required:"true"`
-	LongName    string `gtfs_name:"route_long_name" gtfs_required:"true"`
+	ShortName   string `gtfs_name:"route_short_name" gtfs_required:"conditional"`
+	LongName    string `gtfs_name:"route_long_name" gtfs_required:"conditional"`
 	Description string `gtfs_name:"route_desc" gtfs_required:"false"`
 	Type        string `gtfs_name:"route_type" gtfs_required:"true"`
 	Url         string `gtfs_name:"route_url" gtfs_required:"false"`
 	Color       string `gtfs_name:"route_color" gtfs_required:"false"`
 	TextColor   string `gtfs_name:"route_text_color" gtfs_required:"false"`
 	SortOrder   string `gtfs_name:"route_sort_order" gtfs_required:"false"`
+	NetworkId   string `gtfs_name:"network_id" gtfs_required:"conditional"`
 }
 
 func (r *Route) String() string {
@@ -192,81 +195,205 @@ func (t *Transfer) String() string {
 	return t.FromStopId + " to " + t.ToStopId + " " + t.TransferType
 }
 
-func getFieldIndexForStruct(t reflect.Type, name string) (int, error) {
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		if f.Tag.Get("gtfs_name") == name {
-			return i, nil
-		}
-	}
-
-	return -1, errors.New("Field not found " + name)
-}
-
-func getIfFieldRequired(t reflect.Type, name string) bool {
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		if f.Tag.Get("gtfs_name") == name {
-			if f.Tag.Get("gtfs_required") == "true" {
-				return true
-			} else {
-				return false
-			}
-		}
-	}
-
-	return false
-}
-
-func Decode(r io.Reader, rowtype interface{}) ([]interface{}, error) {
-	c := csv.NewReader(r)
-	columns, err := c.Read()
-	if err != nil {
-		return nil, err
-	}
-
-	if len(columns) == 0 {
-		return nil, errors.New("No fields")
-	}
-
-	fields := make([]int, len(columns))
-	required := make([]bool, len(columns))
-	t := reflect.TypeOf(rowtype).Elem()
-
-	for i := 0; i < len(columns); i++ {
-		fields[i], err = getFieldIndexForStruct(t, columns[i])
-		if err != nil {
-			return nil, err
-		}
-
-		required[i] = getIfFieldRequired(t, columns[i])
-	}
-
-	output := make([]interface{}, 0)
-
-	for {
-		row, err := c.Read()
-		if err == nil {
-			o := reflect.New(t)
-			for i := 0; i < len(row); i++ {
-				value := row[i]
-
-				if required[i] {
-					if value == "" {
-						return nil, errors.New("Row is missing required field " + columns[i])
-					}
-				}
-
-				o.Elem().Field(fields[i]).SetString(value)
-			}
-
-			output = append(output, o.Interface())
-		} else if err == io.EOF {
-			return output, nil
-		} else {
-			return nil, err
-		}
-	}
-
-	return nil, errors.New("Not implemented")
+// From feed_info.txt
+type FeedInfo struct {
+	PublisherName string `gtfs_name:"feed_publisher_name" gtfs_required:"true"`
+	PublisherUrl  string `gtfs_name:"feed_publisher_url" gtfs_required:"true"`
+	Language      string `gtfs_name:"feed_lang" gtfs_required:"true"`
+	DefaultLang   string `gtfs_name:"default_lang" gtfs_required:"false"`
+	StartDate     string `gtfs_name:"feed_start_date" gtfs_required:"false"`
+	EndDate       string `gtfs_name:"feed_end_date" gtfs_required:"false"`
+	Version       string `gtfs_name:"feed_version" gtfs_required:"false"`
+	ContactEmail  string `gtfs_name:"feed_contact_email" gtfs_required:"false"`
+	ContactUrl    string `gtfs_name:"feed_contact_url" gtfs_required:"false"`
+}
+
+func (fi *FeedInfo) String() string {
+	return fi.PublisherName + " " + fi.Version
+}
+
+// From pathways.txt
+type Pathway struct {
+	Id                   string `gtfs_name:"pathway_id" gtfs_required:"true"`
+	FromStopId           string `gtfs_name:"from_stop_id" gtfs_required:"true"`
+	ToStopId             string `gtfs_name:"to_stop_id" gtfs_required:"true"`
+	Mode                 string `gtfs_name:"pathway_mode" gtfs_required:"true"`
+	IsBidirectional      string `gtfs_name:"is_bidirectional" gtfs_required:"true"`
+	Length               string `gtfs_name:"length" gtfs_required:"false"`
+	TraversalTime        string `gtfs_name:"traversal_time" gtfs_required:"false"`
+	StairCount           string `gtfs_name:"stair_count" gtfs_required:"false"`
+	MaxSlope             string `gtfs_name:"max_slope" gtfs_required:"false"`
+	MinWidth             string `gtfs_name:"min_width" gtfs_required:"false"`
+	SignpostedAs         string `gtfs_name:"signposted_as" gtfs_required:"false"`
+	ReversedSignpostedAs string `gtfs_name:"reversed_signposted_as" gtfs_required:"false"`
+}
+
+func (p *Pathway) String() string {
+	return p.Id + " " + p.FromStopId + " to " + p.ToStopId + " mode " + p.Mode
+}
+
+// From levels.txt
+type Level struct {
+	Id    string `gtfs_name:"level_id" gtfs_required:"true"`
+	Index string `gtfs_name:"level_index" gtfs_required:"true"`
+	Name  string `gtfs_name:"level_name" gtfs_required:"false"`
+}
+
+func (l *Level) String() string {
+	return l.Id + " " + l.Index + " " + l.Name
+}
+
+// From translations.txt
+type Translation struct {
+	TableName   string `gtfs_name:"table_name" gtfs_required:"true"`
+	FieldName   string `gtfs_name:"field_name" gtfs_required:"true"`
+	Language    string `gtfs_name:"language" gtfs_required:"true"`
+	Translation string `gtfs_name:"translation" gtfs_required:"true"`
+	RecordId    string `gtfs_name:"record_id" gtfs_required:"conditional"`
+	RecordSubId string `gtfs_name:"record_sub_id" gtfs_required:"conditional"`
+	FieldValue  string `gtfs_name:"field_value" gtfs_required:"conditional"`
+}
+
+func (t *Translation) String() string {
+	return t.TableName + "." + t.FieldName + " " + t.Language + " " + t.Translation
+}
+
+// From attributions.txt
+type Attribution struct {
+	Id               string `gtfs_name:"attribution_id" gtfs_required:"false"`
+	AgencyId         string `gtfs_name:"agency_id" gtfs_required:"false"`
+	RouteId          string `gtfs_name:"route_id" gtfs_required:"false"`
+	TripId           string `gtfs_name:"trip_id" gtfs_required:"false"`
+	OrganizationName string `gtfs_name:"organization_name" gtfs_required:"true"`
+	IsProducer       string `gtfs_name:"is_producer" gtfs_required:"conditional"`
+	IsOperator       string `gtfs_name:"is_operator" gtfs_required:"conditional"`
+	IsAuthority      string `gtfs_name:"is_authority" gtfs_required:"conditional"`
+	Url              string `gtfs_name:"attribution_url" gtfs_required:"false"`
+	Email            string `gtfs_name:"attribution_email" gtfs_required:"false"`
+	Phone            string `gtfs_name:"attribution_phone" gtfs_required:"false"`
+}
+
+func (a *Attribution) String() string {
+	return a.Id + " " + a.OrganizationName
+}
+
+// From fare_media.txt
+type FareMedia struct {
+	Id   string `gtfs_name:"fare_media_id" gtfs_required:"true"`
+	Name string `gtfs_name:"fare_media_name" gtfs_required:"false"`
+	Type string `gtfs_name:"fare_media_type" gtfs_required:"true"`
+}
+
+func (m *FareMedia) String() string {
+	return m.Id + " " + m.Name
+}
+
+// From fare_products.txt. A product can have several rows, one for each rider
+// category and fare media it is sold for.
+type FareProduct struct {
+	Id              string `gtfs_name:"fare_product_id" gtfs_required:"true"`
+	Name            string `gtfs_name:"fare_product_name" gtfs_required:"false"`
+	RiderCategoryId string `gtfs_name:"rider_category_id" gtfs_required:"false"`
+	FareMediaId     string `gtfs_name:"fare_media_id" gtfs_required:"false"`
+	Amount          string `gtfs_name:"amount" gtfs_required:"true"`
+	Currency        string `gtfs_name:"currency" gtfs_required:"true"`
+}
+
+func (p *FareProduct) String() string {
+	return p.Id + " " + p.Amount + p.Currency
+}
+
+// From fare_leg_rules.txt
+type FareLegRule struct {
+	LegGroupId           string `gtfs_name:"leg_group_id" gtfs_required:"false"`
+	NetworkId            string `gtfs_name:"network_id" gtfs_required:"false"`
+	FromAreaId           string `gtfs_name:"from_area_id" gtfs_required:"false"`
+	ToAreaId             string `gtfs_name:"to_area_id" gtfs_required:"false"`
+	FromTimeframeGroupId string `gtfs_name:"from_timeframe_group_id" gtfs_required:"false"`
+	ToTimeframeGroupId   string `gtfs_name:"to_timeframe_group_id" gtfs_required:"false"`
+	FareProductId        string `gtfs_name:"fare_product_id" gtfs_required:"true"`
+	RulePriority         string `gtfs_name:"rule_priority" gtfs_required:"false"`
+}
+
+func (r *FareLegRule) String() string {
+	return r.LegGroupId + " " + r.FromAreaId + " to " + r.ToAreaId + " " + r.FareProductId
+}
+
+// From fare_transfer_rules.txt
+type FareTransferRule struct {
+	FromLegGroupId    string `gtfs_name:"from_leg_group_id" gtfs_required:"false"`
+	ToLegGroupId      string `gtfs_name:"to_leg_group_id" gtfs_required:"false"`
+	TransferCount     string `gtfs_name:"transfer_count" gtfs_required:"conditional"`
+	DurationLimit     string `gtfs_name:"duration_limit" gtfs_required:"false"`
+	DurationLimitType string `gtfs_name:"duration_limit_type" gtfs_required:"conditional"`
+	FareTransferType  string `gtfs_name:"fare_transfer_type" gtfs_required:"true"`
+	FareProductId     string `gtfs_name:"fare_product_id" gtfs_required:"false"`
+}
+
+func (r *FareTransferRule) String() string {
+	return r.FromLegGroupId + " to " + r.ToLegGroupId + " " + r.FareTransferType
+}
+
+// From rider_categories.txt
+type RiderCategory struct {
+	Id             string `gtfs_name:"rider_category_id" gtfs_required:"true"`
+	Name           string `gtfs_name:"rider_category_name" gtfs_required:"true"`
+	IsDefault      string `gtfs_name:"is_default_fare_category" gtfs_required:"true"`
+	EligibilityUrl string `gtfs_name:"eligibility_url" gtfs_required:"false"`
+}
+
+func (c *RiderCategory) String() string {
+	return c.Id + " " + c.Name
+}
+
+// From timeframes.txt
+type Timeframe struct {
+	GroupId   string `gtfs_name:"timeframe_group_id" gtfs_required:"true"`
+	StartTime string `gtfs_name:"start_time" gtfs_required:"conditional"`
+	EndTime   string `gtfs_name:"end_time" gtfs_required:"conditional"`
+	ServiceId string `gtfs_name:"service_id" gtfs_required:"true"`
+}
+
+func (t *Timeframe) String() string {
+	return t.GroupId + " " + t.StartTime + " " + t.EndTime + " " + t.ServiceId
+}
+
+// From areas.txt
+type Area struct {
+	Id   string `gtfs_name:"area_id" gtfs_required:"true"`
+	Name string `gtfs_name:"area_name" gtfs_required:"false"`
+}
+
+func (a *Area) String() string {
+	return a.Id + " " + a.Name
+}
+
+// From stop_areas.txt
+type StopArea struct {
+	AreaId string `gtfs_name:"area_id" gtfs_required:"true"`
+	StopId string `gtfs_name:"stop_id" gtfs_required:"true"`
+}
+
+func (sa *StopArea) String() string {
+	return sa.AreaId + " " + sa.StopId
+}
+
+// From networks.txt
+type Network struct {
+	Id   string `gtfs_name:"network_id" gtfs_required:"true"`
+	Name string `gtfs_name:"network_name" gtfs_required:"false"`
+}
+
+func (n *Network) String() string {
+	return n.Id + " " + n.Name
+}
+
+// From route_networks.txt
+type RouteNetwork struct {
+	NetworkId string `gtfs_name:"network_id" gtfs_required:"true"`
+	RouteId   string `gtfs_name:"route_id" gtfs_required:"true"`
+}
+
+func (rn *RouteNetwork) String() string {
+	return rn.NetworkId + " " + rn.RouteId
 }
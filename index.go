@@ -0,0 +1,203 @@
+package gtfs
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Lookup maps over a feed, built on first use
+type feedIndex struct {
+	agencies        map[string]*Agency
+	stops           map[string]*Stop
+	routes          map[string]*Route
+	trips           map[string]*Trip
+	tripsByRoute    map[string][]*Trip
+	tripsByStop     map[string][]*Trip
+	stopTimesByTrip map[string][]*StopTime
+	stopTimeRows    map[*StopTime]int
+	shapes          map[string][]*ShapePoint
+	levels          map[string]*Level
+	pathwaysByStop  map[string][]*Pathway
+
+	translationsByRecord map[translationKey]*Translation
+	translationsByValue  map[translationKey]*Translation
+}
+
+// Orders sequence numbers numerically, falling back to comparing them as
+// strings when either is not a number
+func sequenceLess(a, b string) bool {
+	x, errx := strconv.Atoi(a)
+	y, erry := strconv.Atoi(b)
+	if errx != nil || erry != nil {
+		return a < b
+	}
+
+	return x < y
+}
+
+func buildIndex(f *Feed) *feedIndex {
+	idx := &feedIndex{
+		agencies:        make(map[string]*Agency, len(f.Agencies)),
+		stops:           make(map[string]*Stop, len(f.Stops)),
+		routes:          make(map[string]*Route, len(f.Routes)),
+		trips:           make(map[string]*Trip, len(f.Trips)),
+		tripsByRoute:    make(map[string][]*Trip),
+		tripsByStop:     make(map[string][]*Trip),
+		stopTimesByTrip: make(map[string][]*StopTime),
+		stopTimeRows:    make(map[*StopTime]int, len(f.StopTimes)),
+		shapes:          make(map[string][]*ShapePoint),
+		levels:          make(map[string]*Level, len(f.Levels)),
+		pathwaysByStop:  make(map[string][]*Pathway),
+
+		translationsByRecord: make(map[translationKey]*Translation),
+		translationsByValue:  make(map[translationKey]*Translation),
+	}
+
+	for _, a := range f.Agencies {
+		idx.agencies[a.Id] = a
+	}
+
+	for _, s := range f.Stops {
+		idx.stops[s.Id] = s
+	}
+
+	for _, r := range f.Routes {
+		idx.routes[r.Id] = r
+	}
+
+	for _, t := range f.Trips {
+		idx.trips[t.Id] = t
+		idx.tripsByRoute[t.RouteId] = append(idx.tripsByRoute[t.RouteId], t)
+	}
+
+	for i, st := range f.StopTimes {
+		idx.stopTimesByTrip[st.TripId] = append(idx.stopTimesByTrip[st.TripId], st)
+		idx.stopTimeRows[st] = i + 1
+	}
+
+	for _, sts := range idx.stopTimesByTrip {
+		sort.SliceStable(sts, func(i, j int) bool {
+			return sequenceLess(sts[i].StopSequence, sts[j].StopSequence)
+		})
+	}
+
+	// Trips serving each stop, in feed order and without repeats for loops
+	for _, t := range f.Trips {
+		seen := make(map[string]bool)
+		for _, st := range idx.stopTimesByTrip[t.Id] {
+			if !seen[st.StopId] {
+				seen[st.StopId] = true
+				idx.tripsByStop[st.StopId] = append(idx.tripsByStop[st.StopId], t)
+			}
+		}
+	}
+
+	for _, sp := range f.ShapePoints {
+		idx.shapes[sp.Id] = append(idx.shapes[sp.Id], sp)
+	}
+
+	for _, sps := range idx.shapes {
+		sort.SliceStable(sps, func(i, j int) bool {
+			return sequenceLess(sps[i].PtSequence, sps[j].PtSequence)
+		})
+	}
+
+	for _, l := range f.Levels {
+		idx.levels[l.Id] = l
+	}
+
+	for _, p := range f.Pathways {
+		idx.pathwaysByStop[p.FromStopId] = append(idx.pathwaysByStop[p.FromStopId], p)
+		if p.ToStopId != p.FromStopId {
+			idx.pathwaysByStop[p.ToStopId] = append(idx.pathwaysByStop[p.ToStopId], p)
+		}
+	}
+
+	// The first of any repeated translations wins
+	for _, t := range f.Translations {
+		if t.FieldValue != "" {
+			k := translationKey{t.TableName, t.FieldName, t.Language, "", "", t.FieldValue}
+			if idx.translationsByValue[k] == nil {
+				idx.translationsByValue[k] = t
+			}
+		} else {
+			k := translationKey{t.TableName, t.FieldName, t.Language, t.RecordId, t.RecordSubId, ""}
+			if idx.translationsByRecord[k] == nil {
+				idx.translationsByRecord[k] = t
+			}
+		}
+	}
+
+	return idx
+}
+
+func (f *Feed) getIndex() *feedIndex {
+	f.indexLock.Lock()
+	defer f.indexLock.Unlock()
+
+	if f.index == nil {
+		f.index = buildIndex(f)
+	}
+
+	return f.index
+}
+
+// Discards the lookup maps so they are rebuilt on next use. Call this after
+// changing the feed's slices.
+func (f *Feed) Reindex() {
+	f.indexLock.Lock()
+	defer f.indexLock.Unlock()
+
+	f.index = nil
+}
+
+// The agency with the given agency_id, or nil
+func (f *Feed) Agency(id string) *Agency {
+	return f.getIndex().agencies[id]
+}
+
+// The stop with the given stop_id, or nil
+func (f *Feed) Stop(id string) *Stop {
+	return f.getIndex().stops[id]
+}
+
+// The route with the given route_id, or nil
+func (f *Feed) Route(id string) *Route {
+	return f.getIndex().routes[id]
+}
+
+// The trip with the given trip_id, or nil
+func (f *Feed) Trip(id string) *Trip {
+	return f.getIndex().trips[id]
+}
+
+// The trips on a route, in feed order
+func (f *Feed) TripsForRoute(routeID string) []*Trip {
+	return f.getIndex().tripsByRoute[routeID]
+}
+
+// The stop times of a trip, ordered by stop_sequence
+func (f *Feed) StopTimesForTrip(tripID string) []*StopTime {
+	return f.getIndex().stopTimesByTrip[tripID]
+}
+
+// The trips which call at a stop, in feed order
+func (f *Feed) TripsServingStop(stopID string) []*Trip {
+	return f.getIndex().tripsByStop[stopID]
+}
+
+// The points of a shape, ordered by shape_pt_sequence
+func (f *Feed) Shape(shapeID string) []*ShapePoint {
+	return f.getIndex().shapes[shapeID]
+}
+
+// The level with the given level_id, or nil
+func (f *Feed) Level(id string) *Level {
+	return f.getIndex().levels[id]
+}
+
+// The pathways starting or ending at a stop, in feed order, whichever way
+// they may be walked
+func (f *Feed) PathwaysForStop(stopID string) []*Pathway {
+	return f.getIndex().pathwaysByStop[stopID]
+}
@@ -0,0 +1,70 @@
+package gtfs
+
+import (
+	"testing"
+)
+
+var testIndexedFiles = map[string]string{
+	"stops.txt": `stop_id,stop_name,stop_lat,stop_lon
+S1,Mission St. & Silver Ave.,37.728631,-122.431282
+S2,Mission St. & Cortland Ave.,37.74103,-122.422482
+S3,Mission St. & 24th St.,37.75223,-122.418581`,
+	"routes.txt": `route_id,route_short_name,route_long_name,route_type
+A,17,Mission,3
+B,18,Valencia,3`,
+	"trips.txt": `route_id,service_id,trip_id,shape_id
+A,WE,AWE1,A_shp
+A,WE,AWE2,A_shp
+B,WE,BWE1,`,
+	"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+AWE1,0:06:20,0:06:20,S3,10
+AWE1,0:06:10,0:06:10,S1,2
+AWE1,0:06:15,0:06:15,S2,9
+AWE2,0:07:10,0:07:10,S1,1
+BWE1,0:08:10,0:08:10,S2,1
+BWE1,0:08:20,0:08:20,S3,2
+BWE1,0:08:30,0:08:30,S2,3`,
+	"shapes.txt": `shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence
+A_shp,37.65863,-122.30839,11
+A_shp,37.61956,-122.48161,1
+A_shp,37.64430,-122.41070,2`,
+}
+
+func TestFeedLookups(t *testing.T) {
+	feed := loadTestFeed(t, testIndexedFiles)
+
+	assert(t, feed.Stop("S2").Name == "Mission St. & Cortland Ave.", "Wrong stop by id")
+	assert(t, feed.Stop("S9") == nil, "Unknown stop should be nil")
+	assert(t, feed.Route("B").LongName == "Valencia", "Wrong route by id")
+	assert(t, feed.Trip("AWE2").RouteId == "A", "Wrong trip by id")
+	assert(t, feed.Agency("FunBus").Name == "The Fun Bus", "Wrong agency by id")
+
+	trips := feed.TripsForRoute("A")
+	assert(t, len(trips) == 2 && trips[0].Id == "AWE1" && trips[1].Id == "AWE2", "Wrong trips for route")
+	assert(t, len(feed.TripsForRoute("Z")) == 0, "Unknown route should have no trips")
+}
+
+func TestFeedStopTimesForTrip(t *testing.T) {
+	feed := loadTestFeed(t, testIndexedFiles)
+
+	sts := feed.StopTimesForTrip("AWE1")
+	if len(sts) != 3 {
+		t.Fatalf("Wrong number of stop times %d", len(sts))
+	}
+	assert(t, sts[0].StopId == "S1" && sts[1].StopId == "S2" && sts[2].StopId == "S3", "Stop times not ordered numerically by stop_sequence")
+
+	sps := feed.Shape("A_shp")
+	assert(t, len(sps) == 3 && sps[0].PtSequence == "1" && sps[2].PtSequence == "11", "Shape points not ordered numerically")
+}
+
+func TestFeedTripsServingStop(t *testing.T) {
+	feed := loadTestFeed(t, testIndexedFiles)
+
+	trips := feed.TripsServingStop("S2")
+	assert(t, len(trips) == 2 && trips[0].Id == "AWE1" && trips[1].Id == "BWE1", "Wrong trips serving stop")
+
+	feed.Trips = feed.Trips[:1]
+	assert(t, len(feed.TripsServingStop("S2")) == 2, "Index should be kept until Reindex")
+	feed.Reindex()
+	assert(t, len(feed.TripsServingStop("S2")) == 1, "Index should be rebuilt after Reindex")
+}
@@ -0,0 +1,240 @@
+package gtfs
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Pathway modes from pathways.txt
+const (
+	PathwayWalkway        = "1"
+	PathwayStairs         = "2"
+	PathwayMovingSidewalk = "3"
+	PathwayEscalator      = "4"
+	PathwayElevator       = "5"
+	PathwayFareGate       = "6"
+	PathwayExitGate       = "7"
+)
+
+// Returned by FindPathwayRoute when the pathways do not connect the two
+// locations, or only do so in ways the options rule out
+var ErrNoPathwayRoute = errors.New("No pathway route")
+
+// Used to estimate traversal times for pathways without a traversal_time
+const (
+	walkingSpeed       = 1.3 // Metres per second
+	secondsPerStair    = 0.6
+	defaultPathwayTime = 30
+)
+
+// The steepest slope a wheelchair can be expected to manage, as a ratio of
+// height to length
+const maxWheelchairSlope = 0.083
+
+// Restrictions on the pathways used by FindPathwayRoute
+type PathwayOptions struct {
+	// Avoid pathways with stairs
+	AvoidStairs bool
+
+	// Use only pathways and locations a wheelchair can use. Implies
+	// AvoidStairs, and also rules out escalators, slopes steeper than 8.3%
+	// and locations with wheelchair_boarding 2.
+	Wheelchair bool
+}
+
+// One pathway of a route, in the direction it is walked
+type PathwayStep struct {
+	Pathway *Pathway
+	From    string
+	To      string
+
+	// Whether the pathway is walked from its to_stop_id to its from_stop_id
+	Reversed bool
+
+	// Estimated seconds to traverse the pathway
+	Time int
+}
+
+// The sign to follow for the step, or "" if there is none
+func (s PathwayStep) SignpostedAs() string {
+	if s.Reversed {
+		return s.Pathway.ReversedSignpostedAs
+	}
+
+	return s.Pathway.SignpostedAs
+}
+
+// A way through a station found by FindPathwayRoute
+type PathwayRoute struct {
+	Steps []PathwayStep
+
+	// Estimated seconds for the whole route
+	Time int
+}
+
+// Estimated seconds to traverse a pathway, from traversal_time if given,
+// otherwise from its length or stair count
+func pathwayTime(p *Pathway) int {
+	if t, err := strconv.Atoi(p.TraversalTime); err == nil && t >= 0 {
+		return t
+	}
+
+	if l, err := strconv.ParseFloat(p.Length, 64); err == nil && l >= 0 {
+		return int(math.Ceil(l / walkingSpeed))
+	}
+
+	if n, err := strconv.Atoi(p.StairCount); err == nil {
+		if n < 0 {
+			n = -n
+		}
+		return int(math.Ceil(float64(n) * secondsPerStair))
+	}
+
+	return defaultPathwayTime
+}
+
+func hasStairs(p *Pathway) bool {
+	n, _ := strconv.Atoi(p.StairCount)
+	return p.Mode == PathwayStairs || n != 0
+}
+
+// Reports whether opts allow walking p, ignoring direction
+func (opts PathwayOptions) allows(p *Pathway) bool {
+	if (opts.AvoidStairs || opts.Wheelchair) && hasStairs(p) {
+		return false
+	}
+
+	if opts.Wheelchair {
+		if p.Mode == PathwayEscalator {
+			return false
+		}
+
+		if slope, err := strconv.ParseFloat(p.MaxSlope, 64); err == nil && math.Abs(slope) > maxWheelchairSlope {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reports whether opts allow passing through the stop with the given ID
+func (opts PathwayOptions) allowsStop(f *Feed, id string) bool {
+	if !opts.Wheelchair {
+		return true
+	}
+
+	s := f.Stop(id)
+	return s == nil || s.WheelchairBoarding != "2"
+}
+
+// A location waiting to be visited, for the priority queue
+type pathwayNode struct {
+	id    string
+	time  int
+	order int
+}
+
+type pathwayQueue []pathwayNode
+
+func (q pathwayQueue) Len() int { return len(q) }
+
+func (q pathwayQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time < q[j].time
+	}
+	return q[i].order < q[j].order
+}
+
+func (q pathwayQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pathwayQueue) Push(x interface{}) { *q = append(*q, x.(pathwayNode)) }
+
+func (q *pathwayQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// Finds the quickest way along pathways.txt between two locations in a
+// station, typically an entrance and a platform. Pathways are only walked
+// backwards when is_bidirectional is 1, and opts can rule out stairs and
+// anything unsuitable for wheelchairs. Returns ErrNoPathwayRoute if there is
+// no way through.
+func (f *Feed) FindPathwayRoute(fromStopID, toStopID string, opts PathwayOptions) (*PathwayRoute, error) {
+	for _, id := range []string{fromStopID, toStopID} {
+		if f.Stop(id) == nil {
+			return nil, errors.New("Unknown stop " + id)
+		}
+	}
+
+	if !opts.allowsStop(f, fromStopID) {
+		return nil, ErrNoPathwayRoute
+	}
+
+	times := map[string]int{fromStopID: 0}
+	via := make(map[string]PathwayStep)
+	done := make(map[string]bool)
+
+	q := &pathwayQueue{{fromStopID, 0, 0}}
+	order := 1
+
+	for q.Len() > 0 {
+		n := heap.Pop(q).(pathwayNode)
+		if done[n.id] {
+			continue
+		}
+		done[n.id] = true
+
+		if n.id == toStopID {
+			break
+		}
+
+		for _, p := range f.PathwaysForStop(n.id) {
+			if !opts.allows(p) {
+				continue
+			}
+
+			step := PathwayStep{Pathway: p, From: n.id, Time: pathwayTime(p)}
+			if p.FromStopId == n.id {
+				step.To = p.ToStopId
+			} else if p.IsBidirectional == "1" {
+				step.To = p.FromStopId
+				step.Reversed = true
+			} else {
+				continue
+			}
+
+			if done[step.To] || !opts.allowsStop(f, step.To) {
+				continue
+			}
+
+			t := n.time + step.Time
+			if old, ok := times[step.To]; ok && old <= t {
+				continue
+			}
+
+			times[step.To] = t
+			via[step.To] = step
+			heap.Push(q, pathwayNode{step.To, t, order})
+			order++
+		}
+	}
+
+	if !done[toStopID] {
+		return nil, ErrNoPathwayRoute
+	}
+
+	route := &PathwayRoute{Time: times[toStopID]}
+	for id := toStopID; id != fromStopID; id = via[id].From {
+		route.Steps = append(route.Steps, via[id])
+	}
+
+	for i, j := 0, len(route.Steps)-1; i < j; i, j = i+1, j-1 {
+		route.Steps[i], route.Steps[j] = route.Steps[j], route.Steps[i]
+	}
+
+	return route, nil
+}
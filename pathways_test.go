@@ -0,0 +1,104 @@
+package gtfs
+
+import (
+	"errors"
+	"testing"
+)
+
+var testStationFiles = map[string]string{
+	"agency.txt": testFeedFiles["agency.txt"],
+	"stops.txt": `stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station,wheelchair_boarding,level_id,platform_code
+STN,Central,37.7752,-122.4186,1,,,,
+E1,Main St. entrance,37.7751,-122.4185,2,STN,2,L0,
+E2,Side St. entrance,37.7753,-122.4187,2,STN,1,L0,
+N1,,,,3,STN,,L0,
+P1,Central platform 1,37.7752,-122.4186,0,STN,1,L1,1
+S1,Mission St. & Silver Ave.,37.728631,-122.431282,,,,,
+S2,Mission St. & Cortland Ave.,37.74103,-122.422482,,,,,`,
+	"routes.txt":     testFeedFiles["routes.txt"],
+	"trips.txt":      testFeedFiles["trips.txt"],
+	"stop_times.txt": testFeedFiles["stop_times.txt"],
+	"calendar.txt":   testFeedFiles["calendar.txt"],
+	"levels.txt": `level_id,level_index,level_name
+L0,0,Street
+L1,-1,Platforms`,
+	"pathways.txt": `pathway_id,from_stop_id,to_stop_id,pathway_mode,is_bidirectional,length,traversal_time,stair_count,signposted_as,reversed_signposted_as
+W1,E1,N1,1,1,13,,,Trains,Main St.
+ST,N1,P1,2,1,,20,20,Platform 1,Way out
+EL,N1,P1,5,1,,40,,Platform 1,Way out
+ESC,E2,P1,4,0,,15,,Platform 1,
+W2,E2,N1,1,1,,30,,Trains,Side St.`,
+}
+
+func stepIds(r *PathwayRoute) string {
+	s := ""
+	for i, step := range r.Steps {
+		if i > 0 {
+			s += ","
+		}
+		s += step.Pathway.Id
+	}
+
+	return s
+}
+
+func TestLoadPathways(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(testStationFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert(t, len(feed.Pathways) == 5, "Wrong number of pathways")
+	assert(t, len(feed.Levels) == 2, "Wrong number of levels")
+	assert(t, feed.Stop("P1").PlatformCode == "1", "Wrong platform code")
+	assert(t, feed.Level(feed.Stop("P1").LevelId).Name == "Platforms", "Wrong platform level")
+	assert(t, len(feed.PathwaysForStop("N1")) == 4 && len(feed.PathwaysForStop("S1")) == 0, "Wrong pathways for node")
+}
+
+func TestFindPathwayRoute(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(testStationFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := feed.FindPathwayRoute("E1", "P1", PathwayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, stepIds(r) == "W1,ST" && r.Time == 30, "Wrong quickest route "+stepIds(r))
+	assert(t, r.Steps[0].From == "E1" && r.Steps[0].To == "N1" && r.Steps[0].Time == 10, "Wrong first step")
+	assert(t, r.Steps[1].SignpostedAs() == "Platform 1", "Wrong sign")
+
+	r, err = feed.FindPathwayRoute("E1", "P1", PathwayOptions{AvoidStairs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, stepIds(r) == "W1,EL" && r.Time == 50, "Wrong step-free route "+stepIds(r))
+
+	_, err = feed.FindPathwayRoute("E1", "P1", PathwayOptions{Wheelchair: true})
+	assert(t, errors.Is(err, ErrNoPathwayRoute), "Inaccessible entrance should have no route")
+
+	r, err = feed.FindPathwayRoute("E2", "P1", PathwayOptions{Wheelchair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, stepIds(r) == "W2,EL" && r.Time == 70, "Wrong wheelchair route "+stepIds(r))
+
+	r, err = feed.FindPathwayRoute("E2", "P1", PathwayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, stepIds(r) == "ESC" && r.Time == 15, "Wrong escalator route "+stepIds(r))
+
+	// The escalator only goes down, so leaving walks the other pathways backwards
+	r, err = feed.FindPathwayRoute("P1", "E2", PathwayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, stepIds(r) == "ST,W2" && r.Time == 50, "Wrong exit route "+stepIds(r))
+	assert(t, r.Steps[0].Reversed && r.Steps[0].From == "P1" && r.Steps[0].To == "N1", "First exit step should be reversed")
+	assert(t, r.Steps[0].SignpostedAs() == "Way out", "Wrong reversed sign")
+
+	_, err = feed.FindPathwayRoute("E1", "NOPE", PathwayOptions{})
+	assert(t, err != nil && !errors.Is(err, ErrNoPathwayRoute), "Unknown stop should be an error")
+}
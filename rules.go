@@ -0,0 +1,333 @@
+package gtfs
+
+import (
+	"reflect"
+	"strings"
+)
+
+// What a Rule requires of its fields
+type RuleKind int
+
+const (
+	// Every field must have a value
+	RuleRequired RuleKind = iota
+
+	// No field may have a value
+	RuleForbidden
+
+	// At least one of the fields must have a value
+	RuleAnyOf
+)
+
+// A requirement on the fields of one file which applies only in some
+// situations. The decoder does not enforce these, and fields which are only
+// sometimes required are tagged gtfs_required:"conditional".
+type Rule struct {
+	// The Code of findings for rows breaking the rule
+	Code string
+
+	Severity Severity
+	File     string
+
+	// The gtfs_name of each field the rule is about
+	Fields []string
+
+	Kind RuleKind
+
+	// Reports whether the rule applies to row, a pointer to one of the file's
+	// structs. A nil When applies to every row.
+	When func(f *Feed, row interface{}) bool
+
+	Message string
+}
+
+// The value of the field tagged name in row, or "" if there is none
+func fieldValue(row interface{}, name string) string {
+	v := reflect.ValueOf(row).Elem()
+	i, err := getFieldIndexForStruct(v.Type(), name)
+	if err != nil {
+		return ""
+	}
+
+	s, _ := formatField(v.Field(i))
+	return s
+}
+
+// Location types as used by the rules, with empty meaning a stop or platform
+func locationType(s *Stop) string {
+	if s.LocationType == "" {
+		return "0"
+	}
+
+	return s.LocationType
+}
+
+func isFirstOrLastStop(f *Feed, st *StopTime) bool {
+	sts := f.StopTimesForTrip(st.TripId)
+	return len(sts) > 0 && (sts[0] == st || sts[len(sts)-1] == st)
+}
+
+// The conditional requirements of the GTFS spec, checked by Validate
+var DefaultRules = []Rule{
+	{
+		Code:     "missing_agency_id",
+		Severity: SeverityError,
+		File:     "agency.txt",
+		Fields:   []string{"agency_id"},
+		Kind:     RuleRequired,
+		When:     func(f *Feed, row interface{}) bool { return len(f.Agencies) > 1 },
+		Message:  "Required when there is more than one agency",
+	},
+	{
+		Code:     "missing_agency_id",
+		Severity: SeverityError,
+		File:     "routes.txt",
+		Fields:   []string{"agency_id"},
+		Kind:     RuleRequired,
+		When:     func(f *Feed, row interface{}) bool { return len(f.Agencies) > 1 },
+		Message:  "Required when there is more than one agency",
+	},
+	{
+		Code:     "missing_agency_id",
+		Severity: SeverityError,
+		File:     "fare_attributes.txt",
+		Fields:   []string{"agency_id"},
+		Kind:     RuleRequired,
+		When:     func(f *Feed, row interface{}) bool { return len(f.Agencies) > 1 },
+		Message:  "Required when there is more than one agency",
+	},
+	{
+		Code:     "missing_stop_location",
+		Severity: SeverityError,
+		File:     "stops.txt",
+		Fields:   []string{"stop_name", "stop_lat", "stop_lon"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			return isOneOf(locationType(row.(*Stop)), []string{"0", "1", "2"})
+		},
+		Message: "Required for stops, stations and entrances",
+	},
+	{
+		Code:     "missing_parent_station",
+		Severity: SeverityError,
+		File:     "stops.txt",
+		Fields:   []string{"parent_station"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			return isOneOf(locationType(row.(*Stop)), []string{"2", "3", "4"})
+		},
+		Message: "Required for entrances, generic nodes and boarding areas",
+	},
+	{
+		Code:     "station_with_parent_station",
+		Severity: SeverityError,
+		File:     "stops.txt",
+		Fields:   []string{"parent_station"},
+		Kind:     RuleForbidden,
+		When: func(f *Feed, row interface{}) bool {
+			return locationType(row.(*Stop)) == "1"
+		},
+		Message: "Stations cannot have a parent station",
+	},
+	{
+		Code:     "missing_route_name",
+		Severity: SeverityError,
+		File:     "routes.txt",
+		Fields:   []string{"route_short_name", "route_long_name"},
+		Kind:     RuleAnyOf,
+		Message:  "A route needs a short name, a long name or both",
+	},
+	{
+		Code:     "missing_trip_edge_times",
+		Severity: SeverityError,
+		File:     "stop_times.txt",
+		Fields:   []string{"arrival_time", "departure_time"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			return isFirstOrLastStop(f, row.(*StopTime))
+		},
+		Message: "Required for the first and last stop of a trip",
+	},
+	{
+		Code:     "missing_timepoint_times",
+		Severity: SeverityError,
+		File:     "stop_times.txt",
+		Fields:   []string{"arrival_time", "departure_time"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			return row.(*StopTime).TimePoint == "1"
+		},
+		Message: "Required when timepoint is 1",
+	},
+	{
+		Code:     "missing_translation_key",
+		Severity: SeverityError,
+		File:     "translations.txt",
+		Fields:   []string{"record_id", "field_value"},
+		Kind:     RuleAnyOf,
+		When: func(f *Feed, row interface{}) bool {
+			return row.(*Translation).TableName != "feed_info"
+		},
+		Message: "Translations need a record_id or a field_value",
+	},
+	{
+		Code:     "record_id_with_field_value",
+		Severity: SeverityError,
+		File:     "translations.txt",
+		Fields:   []string{"record_id", "record_sub_id"},
+		Kind:     RuleForbidden,
+		When: func(f *Feed, row interface{}) bool {
+			return row.(*Translation).FieldValue != ""
+		},
+		Message: "Not allowed with field_value",
+	},
+	{
+		Code:     "missing_record_sub_id",
+		Severity: SeverityError,
+		File:     "translations.txt",
+		Fields:   []string{"record_sub_id"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			t := row.(*Translation)
+			return t.TableName == "stop_times" && t.RecordId != ""
+		},
+		Message: "Required for stop_times with a record_id",
+	},
+	{
+		Code:     "feed_info_translation_key",
+		Severity: SeverityError,
+		File:     "translations.txt",
+		Fields:   []string{"record_id", "record_sub_id", "field_value"},
+		Kind:     RuleForbidden,
+		When: func(f *Feed, row interface{}) bool {
+			return row.(*Translation).TableName == "feed_info"
+		},
+		Message: "Not allowed for feed_info",
+	},
+	{
+		Code:     "route_networks_conflict",
+		Severity: SeverityError,
+		File:     "routes.txt",
+		Fields:   []string{"network_id"},
+		Kind:     RuleForbidden,
+		When:     func(f *Feed, row interface{}) bool { return len(f.RouteNetworks) > 0 },
+		Message:  "Not allowed when route_networks.txt is present",
+	},
+	{
+		Code:     "missing_transfer_count",
+		Severity: SeverityError,
+		File:     "fare_transfer_rules.txt",
+		Fields:   []string{"transfer_count"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			r := row.(*FareTransferRule)
+			return r.FromLegGroupId == r.ToLegGroupId
+		},
+		Message: "Required when from_leg_group_id and to_leg_group_id are the same",
+	},
+	{
+		Code:     "forbidden_transfer_count",
+		Severity: SeverityError,
+		File:     "fare_transfer_rules.txt",
+		Fields:   []string{"transfer_count"},
+		Kind:     RuleForbidden,
+		When: func(f *Feed, row interface{}) bool {
+			r := row.(*FareTransferRule)
+			return r.FromLegGroupId != r.ToLegGroupId
+		},
+		Message: "Not allowed when from_leg_group_id and to_leg_group_id differ",
+	},
+	{
+		Code:     "missing_duration_limit_type",
+		Severity: SeverityError,
+		File:     "fare_transfer_rules.txt",
+		Fields:   []string{"duration_limit_type"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			return row.(*FareTransferRule).DurationLimit != ""
+		},
+		Message: "Required when duration_limit is given",
+	},
+	{
+		Code:     "forbidden_duration_limit_type",
+		Severity: SeverityError,
+		File:     "fare_transfer_rules.txt",
+		Fields:   []string{"duration_limit_type"},
+		Kind:     RuleForbidden,
+		When: func(f *Feed, row interface{}) bool {
+			return row.(*FareTransferRule).DurationLimit == ""
+		},
+		Message: "Not allowed without duration_limit",
+	},
+	{
+		Code:     "missing_timeframe_time",
+		Severity: SeverityError,
+		File:     "timeframes.txt",
+		Fields:   []string{"start_time", "end_time"},
+		Kind:     RuleRequired,
+		When: func(f *Feed, row interface{}) bool {
+			t := row.(*Timeframe)
+			return t.StartTime != "" || t.EndTime != ""
+		},
+		Message: "start_time and end_time must be given together",
+	},
+}
+
+// Checks every row of the feed against rules
+func CheckRules(f *Feed, rules []Rule) []Finding {
+	var out []Finding
+
+	for _, rule := range rules {
+		for _, ff := range feedFiles {
+			if ff.name != rule.File {
+				continue
+			}
+
+			ff.each(f, func(i int, row interface{}) {
+				if rule.When != nil && !rule.When(f, row) {
+					return
+				}
+
+				out = append(out, rule.check(i, row)...)
+			})
+		}
+	}
+
+	return out
+}
+
+func (rule *Rule) check(i int, row interface{}) []Finding {
+	var out []Finding
+
+	add := func(field, value string) {
+		out = append(out, Finding{rule.Severity, rule.Code, rule.File, i + 1, field, value, rule.Message})
+	}
+
+	switch rule.Kind {
+	case RuleRequired:
+		for _, name := range rule.Fields {
+			if fieldValue(row, name) == "" {
+				add(name, "")
+			}
+		}
+	case RuleForbidden:
+		for _, name := range rule.Fields {
+			if v := fieldValue(row, name); v != "" {
+				add(name, v)
+			}
+		}
+	case RuleAnyOf:
+		for _, name := range rule.Fields {
+			if fieldValue(row, name) != "" {
+				return nil
+			}
+		}
+		add(strings.Join(rule.Fields, ", "), "")
+	}
+
+	return out
+}
+
+func checkDefaultRules(f *Feed) []Finding {
+	return CheckRules(f, DefaultRules)
+}
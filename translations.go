@@ -0,0 +1,129 @@
+package gtfs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Identifies translations in the feed's index. Translations matched by
+// record leave value empty, and those matched by field_value leave record and
+// sub empty.
+type translationKey struct {
+	table, field, lang, record, sub, value string
+}
+
+// The tables translations.txt can refer to, with the type of their rows
+var translatableTables = map[string]reflect.Type{
+	"agency":       reflect.TypeOf(Agency{}),
+	"stops":        reflect.TypeOf(Stop{}),
+	"routes":       reflect.TypeOf(Route{}),
+	"trips":        reflect.TypeOf(Trip{}),
+	"stop_times":   reflect.TypeOf(StopTime{}),
+	"pathways":     reflect.TypeOf(Pathway{}),
+	"levels":       reflect.TypeOf(Level{}),
+	"feed_info":    reflect.TypeOf(FeedInfo{}),
+	"attributions": reflect.TypeOf(Attribution{}),
+}
+
+func translatableTableNames() []string {
+	names := make([]string, 0, len(translatableTables))
+	for name := range translatableTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// The row of table identified by recordID and recordSubID, or nil
+func (f *Feed) record(table, recordID, recordSubID string) interface{} {
+	switch table {
+	case "agency":
+		if a := f.Agency(recordID); a != nil {
+			return a
+		}
+	case "stops":
+		if s := f.Stop(recordID); s != nil {
+			return s
+		}
+	case "routes":
+		if r := f.Route(recordID); r != nil {
+			return r
+		}
+	case "trips":
+		if t := f.Trip(recordID); t != nil {
+			return t
+		}
+	case "stop_times":
+		for _, st := range f.StopTimesForTrip(recordID) {
+			if st.StopSequence == recordSubID {
+				return st
+			}
+		}
+	case "pathways":
+		for _, p := range f.Pathways {
+			if p.Id == recordID {
+				return p
+			}
+		}
+	case "levels":
+		if l := f.Level(recordID); l != nil {
+			return l
+		}
+	case "feed_info":
+		if len(f.FeedInfo) > 0 {
+			return f.FeedInfo[0]
+		}
+	case "attributions":
+		for _, a := range f.Attributions {
+			if a.Id == recordID {
+				return a
+			}
+		}
+	}
+
+	return nil
+}
+
+// Translates a field of one row, such as the stop_name of a stop with
+// Translate("stops", "stop_name", stopID, "fr"). A translation for the row's
+// record_id is preferred, then one for the field's value. A lang with a
+// region such as "fr-CA" falls back to "fr". Returns the untranslated value
+// and false if there is no translation.
+func (f *Feed) Translate(table, field, recordID, lang string) (string, bool) {
+	return f.TranslateRecord(table, field, recordID, "", lang)
+}
+
+// Like Translate, for tables such as stop_times whose rows are identified by
+// a record_sub_id as well
+func (f *Feed) TranslateRecord(table, field, recordID, recordSubID, lang string) (string, bool) {
+	row := f.record(table, recordID, recordSubID)
+
+	value := ""
+	if row != nil {
+		value = fieldValue(row, field)
+	}
+
+	idx := f.getIndex()
+	langs := []string{lang}
+	if i := strings.Index(lang, "-"); i > 0 {
+		langs = append(langs, lang[:i])
+	}
+
+	for _, l := range langs {
+		if t := idx.translationsByRecord[translationKey{table, field, l, recordID, recordSubID, ""}]; t != nil {
+			return t.Translation, true
+		}
+
+		if value == "" {
+			continue
+		}
+
+		if t := idx.translationsByValue[translationKey{table, field, l, "", "", value}]; t != nil {
+			return t.Translation, true
+		}
+	}
+
+	return value, false
+}
@@ -0,0 +1,82 @@
+package gtfs
+
+import (
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	feed := loadTestFeed(t, map[string]string{
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign\nA,WE,AWE1,Downtown",
+		"translations.txt": `table_name,field_name,language,translation,record_id,record_sub_id,field_value
+stops,stop_name,es,Calle Mission y Avenida Silver,S1,,
+stops,stop_name,fr,Rue Mission et Avenue Silver,S1,,
+routes,route_long_name,es,Misión,,,Mission
+trips,trip_headsign,es,Centro,,,Downtown
+trips,trip_headsign,es,Centro de la ciudad,AWE1,,
+stop_times,stop_headsign,es,Centro,AWE1,2,
+feed_info,feed_publisher_name,es,El Autobús Divertido,,,`,
+	})
+
+	s, ok := feed.Translate("stops", "stop_name", "S1", "es")
+	assert(t, ok && s == "Calle Mission y Avenida Silver", "Wrong stop name by record "+s)
+
+	s, ok = feed.Translate("stops", "stop_name", "S1", "fr-CA")
+	assert(t, ok && s == "Rue Mission et Avenue Silver", "Regional language should fall back "+s)
+
+	s, ok = feed.Translate("stops", "stop_name", "S2", "es")
+	assert(t, !ok && s == "Mission St. & Cortland Ave.", "Untranslated stop should keep its name "+s)
+
+	s, ok = feed.Translate("routes", "route_long_name", "A", "es")
+	assert(t, ok && s == "Misión", "Wrong route name by value "+s)
+
+	s, ok = feed.Translate("trips", "trip_headsign", "AWE1", "es")
+	assert(t, ok && s == "Centro de la ciudad", "Record should be preferred to value "+s)
+
+	s, ok = feed.TranslateRecord("stop_times", "stop_headsign", "AWE1", "2", "es")
+	assert(t, ok && s == "Centro", "Wrong stop time translation "+s)
+
+	s, ok = feed.Translate("feed_info", "feed_publisher_name", "", "es")
+	assert(t, ok && s == "El Autobús Divertido", "Wrong feed info translation "+s)
+
+	_, ok = feed.Translate("stops", "stop_name", "S9", "es")
+	assert(t, !ok, "Unknown stop should not be translated")
+}
+
+func TestValidateTranslations(t *testing.T) {
+	feed := loadTestFeed(t, map[string]string{
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign\nA,WE,AWE1,Downtown",
+		"translations.txt": `table_name,field_name,language,translation,record_id,record_sub_id,field_value
+stops,stop_name,es,Calle Mission,S9,,
+stops,stop_nombre,es,Calle Mission,S1,,
+calendar,service_id,es,FS,WE,,
+routes,route_long_name,es,Misión,A,,Mission
+stop_times,stop_headsign,es,Centro,AWE1,,
+trips,trip_headsign,es,Centro,,,
+feed_info,feed_version,es,2006.1,,,2006.1`,
+	})
+	feed.FeedInfo = nil
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	codes := []struct {
+		row   int
+		field string
+		code  string
+	}{
+		{1, "record_id", "foreign_key_violation"},
+		{2, "field_name", "unknown_translation_field"},
+		{3, "table_name", "invalid_enum_value"},
+		{4, "record_id", "record_id_with_field_value"},
+		{5, "record_sub_id", "missing_record_sub_id"},
+		{6, "record_id, field_value", "missing_translation_key"},
+		{7, "field_value", "feed_info_translation_key"},
+	}
+	for _, c := range codes {
+		assert(t, findingCode(findings, "translations.txt", c.row, c.field) == c.code, "Missing "+c.code+" for "+c.field)
+	}
+	assert(t, findingCode(findings, "feed_info.txt", 0, "") == "missing_feed_info", "Missing feed info not reported")
+	assert(t, len(findings) == len(codes)+1, "Wrong number of findings")
+}
@@ -0,0 +1,278 @@
+package gtfs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A time of day as used in stop_times.txt and frequencies.txt, counted in
+// seconds from the start of the service day. Times after midnight at the end
+// of the service day are past 24:00:00.
+type ServiceTime int
+
+// Parses H:MM:SS or HH:MM:SS, allowing hours of 24 and over
+func ParseServiceTime(s string) (ServiceTime, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || len(parts[1]) != 2 || len(parts[2]) != 2 || len(parts[0]) == 0 {
+		return 0, errors.New("Invalid time " + s)
+	}
+
+	var hms [3]int
+	for i, p := range parts {
+		if !isDigits(p) {
+			return 0, errors.New("Invalid time " + s)
+		}
+
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, errors.New("Invalid time " + s)
+		}
+		hms[i] = n
+	}
+
+	if hms[1] > 59 || hms[2] > 59 {
+		return 0, errors.New("Invalid time " + s)
+	}
+
+	return ServiceTime(hms[0]*3600 + hms[1]*60 + hms[2]), nil
+}
+
+// Reports whether s is only ASCII digits, so that signs such as in "-0" are
+// not accepted by strconv
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Formats as HH:MM:SS
+func (t ServiceTime) String() string {
+	s := int(t)
+	sign := ""
+	if s < 0 {
+		sign = "-"
+		s = -s
+	}
+
+	return sign + pad2(s/3600) + ":" + pad2(s/60%60) + ":" + pad2(s%60)
+}
+
+// The hours, minutes and seconds of t
+func (t ServiceTime) Clock() (hour, min, sec int) {
+	s := int(t)
+	return s / 3600, s / 60 % 60, s % 60
+}
+
+// t as a duration from the start of the service day
+func (t ServiceTime) Duration() time.Duration {
+	return time.Duration(t) * time.Second
+}
+
+// t+d, truncated to whole seconds
+func (t ServiceTime) Add(d time.Duration) ServiceTime {
+	return t + ServiceTime(d/time.Second)
+}
+
+// The duration t-u
+func (t ServiceTime) Sub(u ServiceTime) time.Duration {
+	return (t - u).Duration()
+}
+
+func (t ServiceTime) Before(u ServiceTime) bool {
+	return t < u
+}
+
+func (t ServiceTime) After(u ServiceTime) bool {
+	return t > u
+}
+
+// Returns -1, 0 or +1 as t is before, equal to or after u
+func (t ServiceTime) Compare(u ServiceTime) int {
+	return compareInts(int(t), int(u))
+}
+
+// The absolute time of t on the service day date in loc. Service times are
+// measured from noon minus 12 hours, which is midnight except on days when
+// daylight saving time starts or ends, when the service day begins at 23:00 or
+// 01:00 on the clock.
+func (t ServiceTime) Time(date Date, loc *time.Location) time.Time {
+	noon := time.Date(date.Year, date.Month, date.Day, 12, 0, 0, 0, loc)
+	return noon.Add(-12 * time.Hour).Add(t.Duration())
+}
+
+// As Time, with the location given by an IANA zone name such as
+// Agency.Timezone
+func (t ServiceTime) In(date Date, timezone string) (time.Time, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.Time(date, loc), nil
+}
+
+func (t ServiceTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *ServiceTime) UnmarshalText(b []byte) error {
+	v, err := ParseServiceTime(string(b))
+	if err != nil {
+		return err
+	}
+
+	*t = v
+	return nil
+}
+
+// A calendar date as used in calendar.txt and calendar_dates.txt
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// Parses YYYYMMDD
+func ParseDate(s string) (Date, error) {
+	if len(s) != 8 {
+		return Date{}, errors.New("Invalid date " + s)
+	}
+
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		return Date{}, errors.New("Invalid date " + s)
+	}
+
+	return DateOf(t), nil
+}
+
+// The date of t in its own location
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// Formats as YYYYMMDD
+func (d Date) String() string {
+	return pad2(d.Year/100) + pad2(d.Year%100) + pad2(int(d.Month)) + pad2(d.Day)
+}
+
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// Midnight at the start of d in loc
+func (d Date) Time(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// The date n days after d
+func (d Date) AddDays(n int) Date {
+	return DateOf(d.Time(time.UTC).AddDate(0, 0, n))
+}
+
+func (d Date) Weekday() time.Weekday {
+	return d.Time(time.UTC).Weekday()
+}
+
+func (d Date) Before(e Date) bool {
+	return d.Compare(e) < 0
+}
+
+func (d Date) After(e Date) bool {
+	return d.Compare(e) > 0
+}
+
+// Returns -1, 0 or +1 as d is before, equal to or after e
+func (d Date) Compare(e Date) int {
+	if d.Year != e.Year {
+		return compareInts(d.Year, e.Year)
+	} else if d.Month != e.Month {
+		return compareInts(int(d.Month), int(e.Month))
+	}
+
+	return compareInts(d.Day, e.Day)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	v, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = v
+	return nil
+}
+
+// An RGB colour as used for route_color and route_text_color
+type Color struct {
+	R, G, B uint8
+}
+
+// Parses a six digit hex colour such as FFFFFF, without a leading #
+func ParseColor(s string) (Color, error) {
+	if len(s) != 6 {
+		return Color{}, errors.New("Invalid color " + s)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return Color{}, errors.New("Invalid color " + s)
+	}
+
+	return Color{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// Formats as six upper case hex digits
+func (c Color) String() string {
+	const digits = "0123456789ABCDEF"
+	b := []byte{
+		digits[c.R>>4], digits[c.R&15],
+		digits[c.G>>4], digits[c.G&15],
+		digits[c.B>>4], digits[c.B&15],
+	}
+
+	return string(b)
+}
+
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Color) UnmarshalText(b []byte) error {
+	v, err := ParseColor(string(b))
+	if err != nil {
+		return err
+	}
+
+	*c = v
+	return nil
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+func pad2(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,100 @@
+package gtfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseServiceTime(t *testing.T) {
+	st, err := ParseServiceTime("25:35:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, st == 25*3600+35*60, "Wrong service time")
+	assert(t, st.String() == "25:35:00", "Wrong service time string "+st.String())
+
+	st, err = ParseServiceTime("0:06:10")
+	assert(t, err == nil && st == 370, "Wrong single digit hour service time")
+	assert(t, st.String() == "00:06:10", "Wrong service time string "+st.String())
+
+	for _, s := range []string{"", "6:10", "06:60:00", "06:10:0", "-1:00:00", "+1:00:00", "a:00:00", "-0:00:00", "-00:00:00", "0:-1:00", "0:00:+1"} {
+		_, err := ParseServiceTime(s)
+		assert(t, err != nil, "Invalid service time accepted "+s)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	d, err := ParseDate("20060701")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, d == Date{2006, time.July, 1}, "Wrong date")
+	assert(t, d.String() == "20060701", "Wrong date string "+d.String())
+
+	for _, s := range []string{"", "2006071", "20061301", "20060230", "2006-07-01"} {
+		_, err := ParseDate(s)
+		assert(t, err != nil, "Invalid date accepted "+s)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("00ff7F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, c == Color{0, 255, 127}, "Wrong color")
+	assert(t, c.String() == "00FF7F", "Wrong color string "+c.String())
+
+	for _, s := range []string{"", "#FFFFFF", "FFF", "GGGGGG"} {
+		_, err := ParseColor(s)
+		assert(t, err != nil, "Invalid color accepted "+s)
+	}
+}
+
+func TestServiceTimeArithmetic(t *testing.T) {
+	a, _ := ParseServiceTime("23:50:00")
+	b := a.Add(20 * time.Minute)
+
+	assert(t, b.String() == "24:10:00", "Wrong service time after add "+b.String())
+	assert(t, b.Sub(a) == 20*time.Minute, "Wrong service time difference")
+	assert(t, a.Before(b) && b.After(a) && !a.After(b), "Wrong service time ordering")
+	assert(t, a.Compare(b) == -1 && b.Compare(a) == 1 && a.Compare(a) == 0, "Wrong service time comparison")
+
+	h, m, s := b.Clock()
+	assert(t, h == 24 && m == 10 && s == 0, "Wrong service time clock")
+}
+
+func TestServiceTimeTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	st, _ := ParseServiceTime("25:35:00")
+	tm := st.Time(Date{2006, time.July, 1}, loc)
+	assert(t, tm.Equal(time.Date(2006, time.July, 2, 1, 35, 0, 0, loc)), "Wrong time past midnight "+tm.String())
+
+	// Daylight saving time started at 02:00 on 12 March 2023, so the service
+	// day began at 23:00 the night before and times after the change match the
+	// clock
+	early, _ := ParseServiceTime("01:30:00")
+	late, _ := ParseServiceTime("08:00:00")
+	tm = early.Time(Date{2023, time.March, 12}, loc)
+	assert(t, tm.Equal(time.Date(2023, time.March, 12, 0, 30, 0, 0, loc)), "Wrong early time on DST start "+tm.String())
+	tm = late.Time(Date{2023, time.March, 12}, loc)
+	assert(t, tm.Equal(time.Date(2023, time.March, 12, 8, 0, 0, 0, loc)), "Wrong late time on DST start "+tm.String())
+
+	// and ended at 02:00 on 5 November 2023, when the service day began at
+	// 01:00 daylight time
+	tm = early.Time(Date{2023, time.November, 5}, loc)
+	assert(t, tm.Equal(time.Date(2023, time.November, 5, 9, 30, 0, 0, time.UTC)), "Wrong early time on DST end "+tm.String())
+	tm = late.Time(Date{2023, time.November, 5}, loc)
+	assert(t, tm.Equal(time.Date(2023, time.November, 5, 8, 0, 0, 0, loc)), "Wrong late time on DST end "+tm.String())
+
+	a := &Agency{Timezone: "America/Los_Angeles"}
+	l, err := a.Location()
+	assert(t, err == nil && l.String() == "America/Los_Angeles", "Wrong agency location")
+
+	_, err = late.In(Date{2023, time.November, 5}, "Not/AZone")
+	assert(t, err != nil, "Invalid timezone accepted")
+}
@@ -0,0 +1,275 @@
+package gtfs
+
+import (
+	"strconv"
+)
+
+// How serious a finding is
+type Severity int
+
+const (
+	// Worth knowing but not a problem
+	SeverityInfo Severity = iota
+
+	// Allowed by the spec but likely to be a mistake or to cause trouble
+	SeverityWarning
+
+	// Not allowed by the spec
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return "severity " + strconv.Itoa(int(s))
+}
+
+// Something found by Validate
+type Finding struct {
+	Severity Severity
+
+	// Identifies the kind of finding, such as foreign_key_violation. Codes do
+	// not change between versions so they can be filtered on.
+	Code string
+
+	File string
+
+	// 1-based position of the row in its file, not counting the header, or
+	// 0 for findings about the file as a whole
+	Row int
+
+	Field   string
+	Value   string
+	Message string
+}
+
+func (p Finding) String() string {
+	s := p.Severity.String() + " " + p.Code + ": " + p.File
+	if p.Row > 0 {
+		s += " row " + strconv.Itoa(p.Row)
+	}
+
+	if p.Field != "" {
+		s += " " + p.Field
+		if p.Value != "" {
+			s += " " + strconv.Quote(p.Value)
+		}
+	}
+
+	return s + ": " + p.Message
+}
+
+type validator func(f *Feed) []Finding
+
+var validators = []validator{
+	checkFields,
+	checkDefaultRules,
+	checkReferences,
+	checkStopTimes,
+	checkFeedInfo,
+	checkFares,
+}
+
+// Checks the feed and returns everything wrong with it
+func Validate(f *Feed) []Finding {
+	findings := make([]Finding, 0)
+	for _, v := range validators {
+		findings = append(findings, v(f)...)
+	}
+
+	return findings
+}
+
+// Checks that every ID referring to another file exists there
+func checkReferences(f *Feed) []Finding {
+	var out []Finding
+
+	missing := func(file string, row int, field, value, target string) {
+		out = append(out, Finding{SeverityError, "foreign_key_violation", file, row + 1, field, value, "Not found in " + target})
+	}
+
+	services := make(map[string]bool)
+	for _, s := range f.Services {
+		services[s.ServiceId] = true
+	}
+	for _, e := range f.ServiceExceptions {
+		services[e.ServiceId] = true
+	}
+
+	fares := make(map[string]bool)
+	for _, fa := range f.Fares {
+		fares[fa.FareId] = true
+	}
+
+	zones := make(map[string]bool)
+	for _, s := range f.Stops {
+		if s.ZoneId != "" {
+			zones[s.ZoneId] = true
+		}
+	}
+
+	for i, s := range f.Stops {
+		if s.ParentStation == "" {
+			continue
+		}
+
+		parent := f.Stop(s.ParentStation)
+		if parent == nil {
+			missing("stops.txt", i, "parent_station", s.ParentStation, "stops.txt")
+			continue
+		}
+
+		// Boarding areas belong to platforms, everything else to stations
+		want := "1"
+		if s.LocationType == "4" {
+			want = "0"
+		}
+
+		got := parent.LocationType
+		if got == "" {
+			got = "0"
+		}
+
+		if got != want {
+			kind := "station"
+			if want == "0" {
+				kind = "platform"
+			}
+			out = append(out, Finding{SeverityError, "wrong_parent_location_type", "stops.txt", i + 1, "parent_station", s.ParentStation, "Parent is not a " + kind})
+		}
+	}
+
+	for i, s := range f.Stops {
+		if s.LevelId != "" && f.Level(s.LevelId) == nil {
+			missing("stops.txt", i, "level_id", s.LevelId, "levels.txt")
+		}
+	}
+
+	for i, r := range f.Routes {
+		if r.AgencyId != "" && f.Agency(r.AgencyId) == nil {
+			missing("routes.txt", i, "agency_id", r.AgencyId, "agency.txt")
+		}
+	}
+
+	for i, t := range f.Trips {
+		if f.Route(t.RouteId) == nil {
+			missing("trips.txt", i, "route_id", t.RouteId, "routes.txt")
+		}
+
+		if !services[t.ServiceId] {
+			missing("trips.txt", i, "service_id", t.ServiceId, "calendar.txt or calendar_dates.txt")
+		}
+
+		if t.ShapeId != "" && len(f.Shape(t.ShapeId)) == 0 {
+			missing("trips.txt", i, "shape_id", t.ShapeId, "shapes.txt")
+		}
+	}
+
+	for i, st := range f.StopTimes {
+		if f.Trip(st.TripId) == nil {
+			missing("stop_times.txt", i, "trip_id", st.TripId, "trips.txt")
+		}
+
+		if f.Stop(st.StopId) == nil {
+			missing("stop_times.txt", i, "stop_id", st.StopId, "stops.txt")
+		}
+	}
+
+	for i, fa := range f.Fares {
+		if fa.AgencyId != "" && f.Agency(fa.AgencyId) == nil {
+			missing("fare_attributes.txt", i, "agency_id", fa.AgencyId, "agency.txt")
+		}
+	}
+
+	for i, fr := range f.FareRules {
+		if !fares[fr.FareId] {
+			missing("fare_rules.txt", i, "fare_id", fr.FareId, "fare_attributes.txt")
+		}
+
+		if fr.RouteId != "" && f.Route(fr.RouteId) == nil {
+			missing("fare_rules.txt", i, "route_id", fr.RouteId, "routes.txt")
+		}
+
+		zoneFields := []struct{ name, value string }{
+			{"origin_id", fr.OriginId},
+			{"destination_id", fr.DestinationId},
+			{"contains_id", fr.ContainsId},
+		}
+		for _, z := range zoneFields {
+			if z.value != "" && !zones[z.value] {
+				missing("fare_rules.txt", i, z.name, z.value, "the zone_id of any stop")
+			}
+		}
+	}
+
+	for i, fq := range f.Frequencies {
+		if f.Trip(fq.TripId) == nil {
+			missing("frequencies.txt", i, "trip_id", fq.TripId, "trips.txt")
+		}
+	}
+
+	for i, t := range f.Transfers {
+		if f.Stop(t.FromStopId) == nil {
+			missing("transfers.txt", i, "from_stop_id", t.FromStopId, "stops.txt")
+		}
+
+		if f.Stop(t.ToStopId) == nil {
+			missing("transfers.txt", i, "to_stop_id", t.ToStopId, "stops.txt")
+		}
+	}
+
+	for i, p := range f.Pathways {
+		ends := []struct{ name, value string }{
+			{"from_stop_id", p.FromStopId},
+			{"to_stop_id", p.ToStopId},
+		}
+		for _, e := range ends {
+			s := f.Stop(e.value)
+			if s == nil {
+				missing("pathways.txt", i, e.name, e.value, "stops.txt")
+			} else if s.LocationType == "1" {
+				out = append(out, Finding{SeverityError, "pathway_to_station", "pathways.txt", i + 1, e.name, e.value, "Pathways cannot start or end at a station"})
+			}
+		}
+	}
+
+	for i, a := range f.Attributions {
+		if a.AgencyId != "" && f.Agency(a.AgencyId) == nil {
+			missing("attributions.txt", i, "agency_id", a.AgencyId, "agency.txt")
+		}
+
+		if a.RouteId != "" && f.Route(a.RouteId) == nil {
+			missing("attributions.txt", i, "route_id", a.RouteId, "routes.txt")
+		}
+
+		if a.TripId != "" && f.Trip(a.TripId) == nil {
+			missing("attributions.txt", i, "trip_id", a.TripId, "trips.txt")
+		}
+	}
+
+	for i, t := range f.Translations {
+		rt, ok := translatableTables[t.TableName]
+		if !ok {
+			continue
+		}
+
+		if _, err := getFieldIndexForStruct(rt, t.FieldName); err != nil {
+			out = append(out, Finding{SeverityError, "unknown_translation_field", "translations.txt", i + 1, "field_name", t.FieldName, "No column " + t.FieldName + " in " + t.TableName + ".txt"})
+		}
+
+		// A stop_times record_id without a record_sub_id is reported by the rules
+		incomplete := t.TableName == "stop_times" && t.RecordSubId == ""
+		if t.RecordId != "" && !incomplete && f.record(t.TableName, t.RecordId, t.RecordSubId) == nil {
+			missing("translations.txt", i, "record_id", t.RecordId, t.TableName+".txt")
+		}
+	}
+
+	return out
+}
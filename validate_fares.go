@@ -0,0 +1,205 @@
+package gtfs
+
+import (
+	"strconv"
+)
+
+// The latest start_time or end_time a timeframe can have
+const maxTimeframeTime = ServiceTime(24 * 60 * 60)
+
+// Checks the Fares v2 files: their fields, and the IDs which refer from one
+// to another and to the rest of the feed
+func checkFares(f *Feed) []Finding {
+	var out []Finding
+
+	add := func(sev Severity, code, file string, row int, field, value, message string) {
+		out = append(out, Finding{sev, code, file, row + 1, field, value, message})
+	}
+
+	missing := func(file string, row int, field, value, target string) {
+		add(SeverityError, "foreign_key_violation", file, row, field, value, "Not found in "+target)
+	}
+
+	integer := func(file string, row int, field, value string, min int) {
+		if value == "" {
+			return
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min {
+			add(SeverityError, "invalid_number", file, row, field, value, "Not a whole number of at least "+strconv.Itoa(min))
+		}
+	}
+
+	media := make(map[string]bool)
+	for _, m := range f.FareMedia {
+		media[m.Id] = true
+	}
+	checkEnum(&out, "fare_media.txt", f.FareMedia, "fare_media_type", func(m *FareMedia) string { return m.Type }, "0", "1", "2", "3", "4")
+
+	categories := make(map[string]bool)
+	defaults := 0
+	for i, c := range f.RiderCategories {
+		categories[c.Id] = true
+
+		if c.EligibilityUrl != "" && !isValidURL(c.EligibilityUrl) {
+			add(SeverityError, "invalid_url", "rider_categories.txt", i, "eligibility_url", c.EligibilityUrl, "Not a full http or https URL")
+		}
+
+		if c.IsDefault == "1" {
+			defaults++
+			if defaults > 1 {
+				add(SeverityError, "multiple_default_rider_categories", "rider_categories.txt", i, "is_default_fare_category", c.IsDefault, "Only one rider category can be the default")
+			}
+		}
+	}
+	checkEnum(&out, "rider_categories.txt", f.RiderCategories, "is_default_fare_category", func(c *RiderCategory) string { return c.IsDefault }, "0", "1")
+
+	products := make(map[string]bool)
+	for i, p := range f.FareProducts {
+		products[p.Id] = true
+
+		if _, err := strconv.ParseFloat(p.Amount, 64); err != nil {
+			add(SeverityError, "invalid_number", "fare_products.txt", i, "amount", p.Amount, "Not a number")
+		}
+
+		if !currencyCodes[p.Currency] {
+			add(SeverityError, "invalid_currency", "fare_products.txt", i, "currency", p.Currency, "Not an ISO 4217 currency code")
+		}
+
+		if p.RiderCategoryId != "" && !categories[p.RiderCategoryId] {
+			missing("fare_products.txt", i, "rider_category_id", p.RiderCategoryId, "rider_categories.txt")
+		}
+
+		if p.FareMediaId != "" && !media[p.FareMediaId] {
+			missing("fare_products.txt", i, "fare_media_id", p.FareMediaId, "fare_media.txt")
+		}
+	}
+
+	areas := make(map[string]bool)
+	for _, a := range f.Areas {
+		areas[a.Id] = true
+	}
+
+	for i, sa := range f.StopAreas {
+		if !areas[sa.AreaId] {
+			missing("stop_areas.txt", i, "area_id", sa.AreaId, "areas.txt")
+		}
+
+		if f.Stop(sa.StopId) == nil {
+			missing("stop_areas.txt", i, "stop_id", sa.StopId, "stops.txt")
+		}
+	}
+
+	// Networks come from networks.txt or the network_id of routes
+	networks := make(map[string]bool)
+	for _, n := range f.Networks {
+		networks[n.Id] = true
+	}
+	for _, r := range f.Routes {
+		if r.NetworkId != "" {
+			networks[r.NetworkId] = true
+		}
+	}
+
+	for i, rn := range f.RouteNetworks {
+		if !networks[rn.NetworkId] {
+			missing("route_networks.txt", i, "network_id", rn.NetworkId, "networks.txt")
+		}
+
+		if f.Route(rn.RouteId) == nil {
+			missing("route_networks.txt", i, "route_id", rn.RouteId, "routes.txt")
+		}
+	}
+
+	services := make(map[string]bool)
+	for _, s := range f.Services {
+		services[s.ServiceId] = true
+	}
+	for _, e := range f.ServiceExceptions {
+		services[e.ServiceId] = true
+	}
+
+	timeframes := make(map[string]bool)
+	for i, t := range f.Timeframes {
+		timeframes[t.GroupId] = true
+
+		if !services[t.ServiceId] {
+			missing("timeframes.txt", i, "service_id", t.ServiceId, "calendar.txt or calendar_dates.txt")
+		}
+
+		times := []struct{ name, value string }{
+			{"start_time", t.StartTime},
+			{"end_time", t.EndTime},
+		}
+		for _, tm := range times {
+			if tm.value == "" {
+				continue
+			}
+
+			v, err := ParseServiceTime(tm.value)
+			if err != nil {
+				add(SeverityError, "invalid_time", "timeframes.txt", i, tm.name, tm.value, "Not a valid time")
+			} else if v.After(maxTimeframeTime) {
+				add(SeverityError, "invalid_time", "timeframes.txt", i, tm.name, tm.value, "Must not be after 24:00:00")
+			}
+		}
+	}
+
+	legGroups := make(map[string]bool)
+	for i, r := range f.FareLegRules {
+		if r.LegGroupId != "" {
+			legGroups[r.LegGroupId] = true
+		}
+
+		if !products[r.FareProductId] {
+			missing("fare_leg_rules.txt", i, "fare_product_id", r.FareProductId, "fare_products.txt")
+		}
+
+		if r.NetworkId != "" && !networks[r.NetworkId] {
+			missing("fare_leg_rules.txt", i, "network_id", r.NetworkId, "networks.txt or the network_id of any route")
+		}
+
+		refs := []struct {
+			name, value, target string
+			known               map[string]bool
+		}{
+			{"from_area_id", r.FromAreaId, "areas.txt", areas},
+			{"to_area_id", r.ToAreaId, "areas.txt", areas},
+			{"from_timeframe_group_id", r.FromTimeframeGroupId, "timeframes.txt", timeframes},
+			{"to_timeframe_group_id", r.ToTimeframeGroupId, "timeframes.txt", timeframes},
+		}
+		for _, ref := range refs {
+			if ref.value != "" && !ref.known[ref.value] {
+				missing("fare_leg_rules.txt", i, ref.name, ref.value, ref.target)
+			}
+		}
+
+		integer("fare_leg_rules.txt", i, "rule_priority", r.RulePriority, 0)
+	}
+
+	for i, r := range f.FareTransferRules {
+		if r.FromLegGroupId != "" && !legGroups[r.FromLegGroupId] {
+			missing("fare_transfer_rules.txt", i, "from_leg_group_id", r.FromLegGroupId, "fare_leg_rules.txt")
+		}
+
+		if r.ToLegGroupId != "" && !legGroups[r.ToLegGroupId] {
+			missing("fare_transfer_rules.txt", i, "to_leg_group_id", r.ToLegGroupId, "fare_leg_rules.txt")
+		}
+
+		if r.FareProductId != "" && !products[r.FareProductId] {
+			missing("fare_transfer_rules.txt", i, "fare_product_id", r.FareProductId, "fare_products.txt")
+		}
+
+		// -1 allows any number of transfers
+		if r.TransferCount != "-1" {
+			integer("fare_transfer_rules.txt", i, "transfer_count", r.TransferCount, 1)
+		}
+
+		integer("fare_transfer_rules.txt", i, "duration_limit", r.DurationLimit, 1)
+	}
+	checkEnum(&out, "fare_transfer_rules.txt", f.FareTransferRules, "duration_limit_type", func(r *FareTransferRule) string { return r.DurationLimitType }, "0", "1", "2", "3")
+	checkEnum(&out, "fare_transfer_rules.txt", f.FareTransferRules, "fare_transfer_type", func(r *FareTransferRule) string { return r.FareTransferType }, "0", "1", "2")
+
+	return out
+}
@@ -0,0 +1,68 @@
+package gtfs
+
+// Checks that there is one row in feed_info.txt if translations need it, that
+// its dates are valid, and that its validity window covers every date with
+// service
+func checkFeedInfo(f *Feed) []Finding {
+	var out []Finding
+
+	add := func(sev Severity, code string, row int, field, value, message string) {
+		out = append(out, Finding{sev, code, "feed_info.txt", row + 1, field, value, message})
+	}
+
+	for i := 1; i < len(f.FeedInfo); i++ {
+		add(SeverityError, "multiple_feed_info", i, "", "", "Only one row is allowed")
+	}
+
+	if len(f.FeedInfo) == 0 {
+		if len(f.Translations) > 0 {
+			add(SeverityError, "missing_feed_info", -1, "", "", "Required when translations.txt is present")
+		}
+		return out
+	}
+
+	fi := f.FeedInfo[0]
+
+	date := func(field, value string) (Date, bool) {
+		if value == "" {
+			return Date{}, false
+		}
+
+		d, err := ParseDate(value)
+		if err != nil {
+			add(SeverityError, "invalid_date", 0, field, value, "Not a YYYYMMDD date")
+			return Date{}, false
+		}
+
+		return d, true
+	}
+
+	start, hasStart := date("feed_start_date", fi.StartDate)
+	end, hasEnd := date("feed_end_date", fi.EndDate)
+
+	if hasStart && hasEnd && end.Before(start) {
+		add(SeverityError, "invalid_date_range", 0, "feed_end_date", fi.EndDate, "Before feed_start_date "+fi.StartDate)
+		return out
+	}
+
+	// Problems with the calendar itself are reported by other checks
+	cal, err := f.Calendar()
+	if err != nil {
+		return out
+	}
+
+	first, last, ok := cal.DateRange()
+	if !ok {
+		return out
+	}
+
+	if hasStart && first.Before(start) {
+		add(SeverityWarning, "service_before_feed_start", 0, "feed_start_date", fi.StartDate, "Service runs from "+first.String())
+	}
+
+	if hasEnd && last.After(end) {
+		add(SeverityWarning, "service_after_feed_end", 0, "feed_end_date", fi.EndDate, "Service runs until "+last.String())
+	}
+
+	return out
+}
@@ -0,0 +1,313 @@
+package gtfs
+
+import (
+	"math"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embedded so that timezones are checked, and ServiceTime.In converts,
+	// the same way whether or not the host has a zone database
+	_ "time/tzdata"
+)
+
+// Active ISO 4217 currency codes
+var currencyCodes = map[string]bool{}
+
+func init() {
+	codes := `AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND
+		BOB BOV BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC
+		CUC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD
+		GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR
+		KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP
+		MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP
+		PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD
+		SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI
+		UYU UYW UZS VED VES VND VUV WST XAF XAG XAU XBA XBB XBC XBD XCD XCG XDR XOF
+		XPD XPF XPT XSU XTS XUA XXX YER ZAR ZMW ZWG ZWL`
+
+	for _, c := range strings.Fields(codes) {
+		currencyCodes[c] = true
+	}
+}
+
+// The route_type values from the spec
+var basicRouteTypes = []string{"0", "1", "2", "3", "4", "5", "6", "7", "11", "12"}
+
+// Ranges of the widely supported extended route types
+var extendedRouteTypes = [][2]int{
+	{100, 117}, {200, 209}, {400, 405}, {700, 716}, {800, 800}, {900, 906},
+	{1000, 1000}, {1100, 1100}, {1200, 1200}, {1300, 1307}, {1400, 1400},
+	{1500, 1507}, {1700, 1702},
+}
+
+func isExtendedRouteType(s string) bool {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return false
+	}
+
+	for _, r := range extendedRouteTypes {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isValidURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isValidEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Name == "" && a.Address == s
+}
+
+func isValidTimezone(s string) bool {
+	if s == "" || s == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(s)
+	return err == nil
+}
+
+// Relative luminance as defined by WCAG 2
+func luminance(c Color) float64 {
+	channel := func(v uint8) float64 {
+		x := float64(v) / 255
+		if x <= 0.03928 {
+			return x / 12.92
+		}
+		return math.Pow((x+0.055)/1.055, 2.4)
+	}
+
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// The WCAG 2 contrast ratio between two colours, from 1 to 21
+func contrastRatio(a, b Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// Below this route_color and route_text_color are hard to read together
+const minContrastRatio = 4.5
+
+func isOneOf(v string, allowed []string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reports optional values which are not among the allowed values
+func checkEnum[T any](out *[]Finding, file string, rows []*T, field string, get func(*T) string, allowed ...string) {
+	for i, r := range rows {
+		v := get(r)
+		if v != "" && !isOneOf(v, allowed) {
+			*out = append(*out, Finding{SeverityError, "invalid_enum_value", file, i + 1, field, v, "Must be one of " + strings.Join(allowed, ", ")})
+		}
+	}
+}
+
+// Checks the contents of individual fields
+func checkFields(f *Feed) []Finding {
+	var out []Finding
+
+	add := func(sev Severity, code, file string, row int, field, value, message string) {
+		out = append(out, Finding{sev, code, file, row + 1, field, value, message})
+	}
+
+	coordinates := func(file string, row int, latField, lat, lonField, lon string) {
+		if lat == "" || lon == "" {
+			return
+		}
+
+		y, err := strconv.ParseFloat(lat, 64)
+		if err != nil {
+			add(SeverityError, "invalid_number", file, row, latField, lat, "Not a number")
+		} else if y < -90 || y > 90 {
+			add(SeverityError, "latitude_out_of_range", file, row, latField, lat, "Must be between -90 and 90")
+		}
+
+		x, err2 := strconv.ParseFloat(lon, 64)
+		if err2 != nil {
+			add(SeverityError, "invalid_number", file, row, lonField, lon, "Not a number")
+		} else if x < -180 || x > 180 {
+			add(SeverityError, "longitude_out_of_range", file, row, lonField, lon, "Must be between -180 and 180")
+		}
+
+		if err == nil && err2 == nil && math.Abs(x) < 1 && math.Abs(y) < 1 {
+			add(SeverityWarning, "point_near_origin", file, row, latField, lat, "Point is next to 0, 0")
+		}
+	}
+
+	urls := func(file string, row int, field, value string) {
+		if value != "" && !isValidURL(value) {
+			add(SeverityError, "invalid_url", file, row, field, value, "Not a full http or https URL")
+		}
+	}
+
+	for i, a := range f.Agencies {
+		if !isValidTimezone(a.Timezone) {
+			add(SeverityError, "invalid_timezone", "agency.txt", i, "agency_timezone", a.Timezone, "Not an IANA time zone")
+		}
+
+		urls("agency.txt", i, "agency_url", a.Url)
+		urls("agency.txt", i, "agency_fare_url", a.FareUrl)
+
+		if a.Email != "" && !isValidEmail(a.Email) {
+			add(SeverityError, "invalid_email", "agency.txt", i, "agency_email", a.Email, "Not an email address")
+		}
+	}
+
+	for i, s := range f.Stops {
+		coordinates("stops.txt", i, "stop_lat", s.Latitude, "stop_lon", s.Longitude)
+		urls("stops.txt", i, "stop_url", s.Url)
+
+		if s.Timezone != "" && !isValidTimezone(s.Timezone) {
+			add(SeverityError, "invalid_timezone", "stops.txt", i, "stop_timezone", s.Timezone, "Not an IANA time zone")
+		}
+	}
+	checkEnum(&out, "stops.txt", f.Stops, "location_type", func(s *Stop) string { return s.LocationType }, "0", "1", "2", "3", "4")
+	checkEnum(&out, "stops.txt", f.Stops, "wheelchair_boarding", func(s *Stop) string { return s.WheelchairBoarding }, "0", "1", "2")
+
+	for i, r := range f.Routes {
+		urls("routes.txt", i, "route_url", r.Url)
+
+		if !isOneOf(r.Type, basicRouteTypes) {
+			if isExtendedRouteType(r.Type) {
+				add(SeverityInfo, "extended_route_type", "routes.txt", i, "route_type", r.Type, "Extended route type, not part of the spec")
+			} else {
+				add(SeverityError, "invalid_enum_value", "routes.txt", i, "route_type", r.Type, "Must be one of "+strings.Join(basicRouteTypes, ", ")+" or an extended route type")
+			}
+		}
+
+		color, text := Color{255, 255, 255}, Color{0, 0, 0}
+		valid := true
+		if r.Color != "" {
+			c, err := ParseColor(r.Color)
+			if err != nil {
+				add(SeverityError, "invalid_color", "routes.txt", i, "route_color", r.Color, "Must be six hex digits")
+				valid = false
+			}
+			color = c
+		}
+
+		if r.TextColor != "" {
+			c, err := ParseColor(r.TextColor)
+			if err != nil {
+				add(SeverityError, "invalid_color", "routes.txt", i, "route_text_color", r.TextColor, "Must be six hex digits")
+				valid = false
+			}
+			text = c
+		}
+
+		if valid && (r.Color != "" || r.TextColor != "") && contrastRatio(color, text) < minContrastRatio {
+			ratio := strconv.FormatFloat(contrastRatio(color, text), 'f', 1, 64)
+			add(SeverityWarning, "low_contrast_colors", "routes.txt", i, "route_text_color", r.TextColor, "Contrast with route_color is only "+ratio+":1")
+		}
+	}
+
+	checkEnum(&out, "trips.txt", f.Trips, "direction_id", func(t *Trip) string { return t.DirectionId }, "0", "1")
+	checkEnum(&out, "trips.txt", f.Trips, "wheelchair_accessible", func(t *Trip) string { return t.WheelchairAccessible }, "0", "1", "2")
+	checkEnum(&out, "trips.txt", f.Trips, "bikes_allowed", func(t *Trip) string { return t.BikesAllowed }, "0", "1", "2")
+
+	checkEnum(&out, "stop_times.txt", f.StopTimes, "pickup_type", func(st *StopTime) string { return st.PickupType }, "0", "1", "2", "3")
+	checkEnum(&out, "stop_times.txt", f.StopTimes, "drop_off_type", func(st *StopTime) string { return st.DropOffType }, "0", "1", "2", "3")
+	checkEnum(&out, "stop_times.txt", f.StopTimes, "timepoint", func(st *StopTime) string { return st.TimePoint }, "0", "1")
+
+	days := []struct {
+		name string
+		get  func(s *Service) string
+	}{
+		{"monday", func(s *Service) string { return s.Monday }},
+		{"tuesday", func(s *Service) string { return s.Tuesday }},
+		{"wednesday", func(s *Service) string { return s.Wednesday }},
+		{"thursday", func(s *Service) string { return s.Thursday }},
+		{"friday", func(s *Service) string { return s.Friday }},
+		{"saturday", func(s *Service) string { return s.Saturday }},
+		{"sunday", func(s *Service) string { return s.Sunday }},
+	}
+	for _, d := range days {
+		checkEnum(&out, "calendar.txt", f.Services, d.name, d.get, "0", "1")
+	}
+
+	checkEnum(&out, "calendar_dates.txt", f.ServiceExceptions, "exception_type", func(e *ServiceException) string { return e.ExceptionType }, ExceptionAdded, ExceptionRemoved)
+
+	for i, fa := range f.Fares {
+		if !currencyCodes[fa.CurrencyType] {
+			add(SeverityError, "invalid_currency", "fare_attributes.txt", i, "currency_type", fa.CurrencyType, "Not an ISO 4217 currency code")
+		}
+	}
+	checkEnum(&out, "fare_attributes.txt", f.Fares, "payment_method", func(fa *Fare) string { return fa.PaymentMethod }, "0", "1")
+	checkEnum(&out, "fare_attributes.txt", f.Fares, "transfers", func(fa *Fare) string { return fa.Transfers }, "0", "1", "2")
+
+	for i, sp := range f.ShapePoints {
+		coordinates("shapes.txt", i, "shape_pt_lat", sp.PtLatitude, "shape_pt_lon", sp.PtLongitude)
+	}
+
+	checkEnum(&out, "frequencies.txt", f.Frequencies, "exact_times", func(fq *Frequency) string { return fq.ExactTimes }, "0", "1")
+
+	checkEnum(&out, "transfers.txt", f.Transfers, "transfer_type", func(t *Transfer) string { return t.TransferType }, "0", "1", "2", "3", "4", "5")
+
+	for i, fi := range f.FeedInfo {
+		urls("feed_info.txt", i, "feed_publisher_url", fi.PublisherUrl)
+		urls("feed_info.txt", i, "feed_contact_url", fi.ContactUrl)
+
+		if fi.ContactEmail != "" && !isValidEmail(fi.ContactEmail) {
+			add(SeverityError, "invalid_email", "feed_info.txt", i, "feed_contact_email", fi.ContactEmail, "Not an email address")
+		}
+	}
+
+	checkEnum(&out, "pathways.txt", f.Pathways, "pathway_mode", func(p *Pathway) string { return p.Mode }, PathwayWalkway, PathwayStairs, PathwayMovingSidewalk, PathwayEscalator, PathwayElevator, PathwayFareGate, PathwayExitGate)
+	checkEnum(&out, "pathways.txt", f.Pathways, "is_bidirectional", func(p *Pathway) string { return p.IsBidirectional }, "0", "1")
+
+	for i, a := range f.Attributions {
+		urls("attributions.txt", i, "attribution_url", a.Url)
+
+		if a.Email != "" && !isValidEmail(a.Email) {
+			add(SeverityError, "invalid_email", "attributions.txt", i, "attribution_email", a.Email, "Not an email address")
+		}
+
+		if roles := attributionRoles(a); !roles[0] && !roles[1] && !roles[2] {
+			add(SeverityError, "missing_attribution_role", "attributions.txt", i, "is_producer, is_operator, is_authority", "", "At least one must be 1")
+		}
+
+		entities := 0
+		for _, id := range []string{a.AgencyId, a.RouteId, a.TripId} {
+			if id != "" {
+				entities++
+			}
+		}
+		if entities > 1 {
+			add(SeverityError, "multiple_attribution_entities", "attributions.txt", i, "agency_id, route_id, trip_id", "", "Only one may be given")
+		}
+	}
+	checkEnum(&out, "attributions.txt", f.Attributions, "is_producer", func(a *Attribution) string { return a.IsProducer }, "0", "1")
+	checkEnum(&out, "attributions.txt", f.Attributions, "is_operator", func(a *Attribution) string { return a.IsOperator }, "0", "1")
+	checkEnum(&out, "attributions.txt", f.Attributions, "is_authority", func(a *Attribution) string { return a.IsAuthority }, "0", "1")
+
+	checkEnum(&out, "translations.txt", f.Translations, "table_name", func(t *Translation) string { return t.TableName }, translatableTableNames()...)
+
+	for i, p := range f.Pathways {
+		if p.Mode == PathwayExitGate && p.IsBidirectional == "1" {
+			add(SeverityError, "bidirectional_exit_gate", "pathways.txt", i, "is_bidirectional", p.IsBidirectional, "Exit gates only go one way")
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,439 @@
+package gtfs
+
+import (
+	"testing"
+)
+
+func hasFinding(findings []Finding, file string, row int, field, value string) bool {
+	for _, f := range findings {
+		if f.File == file && f.Row == row && f.Field == field && f.Value == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestValidateValidFeed(t *testing.T) {
+	feed, err := LoadFS(makeMapFS(testFeedFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Error(f.String())
+	}
+}
+
+func TestValidateReferences(t *testing.T) {
+	files := map[string]string{
+		"agency.txt": testFeedFiles["agency.txt"],
+		"stops.txt": `stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station,zone_id
+S1,Mission St.,37.728631,-122.431282,,STN,1
+S2,Cortland Ave.,37.74103,-122.422482,,S1,2
+S3,24th St.,37.75223,-122.418581,,NOPE,
+STN,Station,37.75223,-122.418581,1,,`,
+		"routes.txt": `route_id,agency_id,route_short_name,route_long_name,route_type
+A,FunBus,17,Mission,3
+B,OtherBus,18,Valencia,3`,
+		"trips.txt": `route_id,service_id,trip_id
+A,WE,AWE1
+C,XX,CXX1`,
+		"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+AWE1,0:06:10,0:06:10,S1,1
+AWE1,0:06:20,0:06:20,S9,2
+ZZZ1,0:06:20,0:06:20,S1,1`,
+		"calendar.txt":        testFeedFiles["calendar.txt"],
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfers\n1,1.50,USD,0,0",
+		"fare_rules.txt":      "fare_id,route_id,origin_id,destination_id\n1,A,1,2\n2,Q,1,7",
+	}
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	assert(t, hasFinding(findings, "stops.txt", 2, "parent_station", "S1"), "Parent which is not a station not reported")
+	assert(t, hasFinding(findings, "stops.txt", 3, "parent_station", "NOPE"), "Unknown parent station not reported")
+	assert(t, hasFinding(findings, "routes.txt", 2, "agency_id", "OtherBus"), "Unknown agency not reported")
+	assert(t, hasFinding(findings, "trips.txt", 2, "route_id", "C"), "Unknown route not reported")
+	assert(t, hasFinding(findings, "trips.txt", 2, "service_id", "XX"), "Unknown service not reported")
+	assert(t, hasFinding(findings, "stop_times.txt", 2, "stop_id", "S9"), "Unknown stop not reported")
+	assert(t, hasFinding(findings, "stop_times.txt", 3, "trip_id", "ZZZ1"), "Unknown trip not reported")
+	assert(t, hasFinding(findings, "fare_rules.txt", 2, "fare_id", "2"), "Unknown fare not reported")
+	assert(t, hasFinding(findings, "fare_rules.txt", 2, "route_id", "Q"), "Unknown fare rule route not reported")
+	assert(t, hasFinding(findings, "fare_rules.txt", 2, "destination_id", "7"), "Unknown zone not reported")
+
+	// Other validators also report problems in these files, so only the
+	// reference findings are counted
+	references := 0
+	for _, f := range findings {
+		if f.Code == "foreign_key_violation" || f.Code == "wrong_parent_location_type" {
+			references++
+		}
+	}
+	assert(t, references == 10, "Wrong number of reference findings")
+}
+
+func findingCode(findings []Finding, file string, row int, field string) string {
+	for _, f := range findings {
+		if f.File == file && f.Row == row && f.Field == field {
+			return f.Code
+		}
+	}
+
+	return ""
+}
+
+func TestValidateFields(t *testing.T) {
+	files := map[string]string{
+		"agency.txt": `agency_id,agency_name,agency_url,agency_timezone,agency_email
+FunBus,The Fun Bus,http://www.thefunbus.org,America/Los_Angeles,info@thefunbus.org
+Other,Other Bus,www.otherbus.org,Mars/Olympus_Mons,not an email`,
+		"stops.txt": `stop_id,stop_name,stop_lat,stop_lon,location_type,stop_timezone
+S1,Mission St.,97.728631,-122.431282,,
+S2,Cortland Ave.,37.74103,-222.422482,7,America/New_York
+S3,24th St.,0.0,0.0,,Nowhere`,
+		"routes.txt": `route_id,route_short_name,route_long_name,route_type,route_color,route_text_color
+A,17,Mission,3,FFFF00,FFFFFF
+B,18,Valencia,715,000000,FFFFFF
+C,19,Polk,42,GG0000,`,
+		"trips.txt": testFeedFiles["trips.txt"],
+		"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence,pickup_type
+AWE1,0:06:10,0:06:10,S1,1,0
+AWE1,0:06:20,0:06:20,S2,2,9`,
+		"calendar.txt":        testFeedFiles["calendar.txt"],
+		"calendar_dates.txt":  "service_id,date,exception_type\nWE,20060703,3",
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfers\n1,1.50,USD,0,0\n2,1.50,XYZ,0,0",
+		"transfers.txt":       "from_stop_id,to_stop_id,transfer_type\nS1,S2,6",
+	}
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	codes := []struct {
+		file  string
+		row   int
+		field string
+		code  string
+	}{
+		{"agency.txt", 2, "agency_url", "invalid_url"},
+		{"agency.txt", 2, "agency_timezone", "invalid_timezone"},
+		{"agency.txt", 2, "agency_email", "invalid_email"},
+		{"stops.txt", 1, "stop_lat", "latitude_out_of_range"},
+		{"stops.txt", 2, "stop_lon", "longitude_out_of_range"},
+		{"stops.txt", 2, "location_type", "invalid_enum_value"},
+		{"stops.txt", 3, "stop_lat", "point_near_origin"},
+		{"stops.txt", 3, "stop_timezone", "invalid_timezone"},
+		{"routes.txt", 1, "route_text_color", "low_contrast_colors"},
+		{"routes.txt", 2, "route_type", "extended_route_type"},
+		{"routes.txt", 3, "route_type", "invalid_enum_value"},
+		{"routes.txt", 3, "route_color", "invalid_color"},
+		{"stop_times.txt", 2, "pickup_type", "invalid_enum_value"},
+		{"calendar_dates.txt", 1, "exception_type", "invalid_enum_value"},
+		{"fare_attributes.txt", 2, "currency_type", "invalid_currency"},
+		{"transfers.txt", 1, "transfer_type", "invalid_enum_value"},
+	}
+
+	for _, c := range codes {
+		got := findingCode(findings, c.file, c.row, c.field)
+		assert(t, got == c.code, "Expected "+c.code+" for "+c.file+" "+c.field+", got "+got)
+	}
+
+	assert(t, findingCode(findings, "agency.txt", 1, "agency_email") == "", "Valid email reported")
+	assert(t, findingCode(findings, "routes.txt", 2, "route_text_color") == "", "Good contrast reported")
+
+	fieldFindings := 0
+	for _, f := range findings {
+		if f.Code != "missing_agency_id" {
+			fieldFindings++
+		}
+	}
+	assert(t, fieldFindings == len(codes), "Wrong number of findings")
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{SeverityError, "foreign_key_violation", "trips.txt", 3, "route_id", "C", "Not found in routes.txt"}
+	assert(t, f.String() == `error foreign_key_violation: trips.txt row 3 route_id "C": Not found in routes.txt`, "Wrong finding text "+f.String())
+}
+
+func TestValidateConditionalRules(t *testing.T) {
+	files := map[string]string{
+		"agency.txt": `agency_id,agency_name,agency_url,agency_timezone
+FunBus,The Fun Bus,http://www.thefunbus.org,America/Los_Angeles
+,Other Bus,http://www.otherbus.org,America/Los_Angeles`,
+		"stops.txt": `stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station
+S1,Mission St.,37.728631,-122.431282,,STN
+S2,,,,,
+STN,Station,37.75223,-122.418581,1,STN2
+STN2,Other Station,37.75223,-122.418581,1,
+N1,,,,3,STN
+E1,Entrance,37.75223,-122.418581,2,`,
+		"routes.txt": `route_id,agency_id,route_short_name,route_long_name,route_type
+A,FunBus,17,,3
+B,FunBus,,,3`,
+		"trips.txt": testFeedFiles["trips.txt"],
+		"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence,timepoint
+AWE1,0:06:10,0:06:10,S1,1,
+AWE1,,,S2,2,
+AWE1,,,S1,3,1
+AWE1,,0:06:30,S2,4,`,
+		"calendar.txt": testFeedFiles["calendar.txt"],
+	}
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := CheckRules(feed, DefaultRules)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	codes := []struct {
+		file  string
+		row   int
+		field string
+		code  string
+	}{
+		{"agency.txt", 2, "agency_id", "missing_agency_id"},
+		{"stops.txt", 2, "stop_name", "missing_stop_location"},
+		{"stops.txt", 2, "stop_lat", "missing_stop_location"},
+		{"stops.txt", 2, "stop_lon", "missing_stop_location"},
+		{"stops.txt", 3, "parent_station", "station_with_parent_station"},
+		{"stops.txt", 6, "parent_station", "missing_parent_station"},
+		{"routes.txt", 2, "route_short_name, route_long_name", "missing_route_name"},
+		{"stop_times.txt", 3, "arrival_time", "missing_timepoint_times"},
+		{"stop_times.txt", 3, "departure_time", "missing_timepoint_times"},
+		{"stop_times.txt", 4, "arrival_time", "missing_trip_edge_times"},
+	}
+
+	for _, c := range codes {
+		got := findingCode(findings, c.file, c.row, c.field)
+		assert(t, got == c.code, "Expected "+c.code+" for "+c.file+" "+c.field+", got "+got)
+	}
+
+	assert(t, findingCode(findings, "stops.txt", 5, "stop_name") == "", "Generic node should not need a name")
+	assert(t, len(findings) == len(codes), "Wrong number of findings")
+}
+
+func TestValidateTrip(t *testing.T) {
+	files := map[string]string{
+		"agency.txt": testFeedFiles["agency.txt"],
+		"stops.txt": `stop_id,stop_name,stop_lat,stop_lon
+S1,Mission St.,37.728631,-122.431282
+S2,Cortland Ave.,37.74103,-122.422482
+S3,24th St.,37.75223,-122.418581
+FAR,Los Angeles,34.0522,-118.2437`,
+		"routes.txt": testFeedFiles["routes.txt"],
+		"trips.txt": `route_id,service_id,trip_id
+A,WE,GOOD
+A,WE,DUP
+A,WE,BACK
+A,WE,FAST`,
+		"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+GOOD,0:06:10,0:06:10,S1,1
+GOOD,0:08:30,0:08:30,S3,3
+GOOD,,,S2,2
+DUP,0:06:10,0:06:10,S1,1
+DUP,0:06:20,0:06:20,S2,01
+DUP,0:06:30,0:06:30,S3,2
+BACK,0:06:10,0:06:10,S1,1
+BACK,0:06:20,0:06:15,S2,2
+BACK,0:06:05,0:06:05,S3,3
+FAST,0:06:10,0:06:10,S1,1
+FAST,0:36:10,0:36:10,FAR,2`,
+		"calendar.txt": testFeedFiles["calendar.txt"],
+	}
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good := ValidateTrip(feed, "GOOD")
+	assert(t, len(good) == 1 && good[0].Code == "unsorted_stop_sequence", "Wrong findings for good trip")
+
+	dup := ValidateTrip(feed, "DUP")
+	assert(t, len(dup) == 1 && dup[0].Code == "duplicate_stop_sequence" && dup[0].Row == 5, "Duplicate stop sequence not reported")
+
+	back := ValidateTrip(feed, "BACK")
+	for _, f := range back {
+		t.Log(f.String())
+	}
+	assert(t, findingCode(back, "stop_times.txt", 8, "departure_time") == "departure_before_arrival", "Departure before arrival not reported")
+	assert(t, findingCode(back, "stop_times.txt", 9, "arrival_time") == "decreasing_stop_time", "Decreasing time not reported")
+
+	fast := ValidateTrip(feed, "FAST")
+	for _, f := range fast {
+		t.Log(f.String())
+	}
+	assert(t, len(fast) == 1 && fast[0].Code == "fast_travel" && fast[0].Row == 11, "Fast travel not reported")
+
+	all := Validate(feed)
+	assert(t, findingCode(all, "stop_times.txt", 11, "arrival_time") == "fast_travel", "Validate should check trips")
+}
+
+func TestValidateFeedInfo(t *testing.T) {
+	files := map[string]string{
+		"agency.txt":     testFeedFiles["agency.txt"],
+		"stops.txt":      testFeedFiles["stops.txt"],
+		"routes.txt":     testFeedFiles["routes.txt"],
+		"trips.txt":      testFeedFiles["trips.txt"],
+		"stop_times.txt": testFeedFiles["stop_times.txt"],
+		"calendar.txt":   testFeedFiles["calendar.txt"],
+		"feed_info.txt": `feed_publisher_name,feed_publisher_url,feed_lang,feed_start_date,feed_end_date,feed_contact_email
+The Fun Bus,http://www.thefunbus.org,en,20060702,20060729,nobody
+Other,http://example.com,en,,,`,
+	}
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	assert(t, findingCode(findings, "feed_info.txt", 1, "feed_start_date") == "service_before_feed_start", "Service before feed start not reported")
+	assert(t, findingCode(findings, "feed_info.txt", 1, "feed_end_date") == "service_after_feed_end", "Service after feed end not reported")
+	assert(t, findingCode(findings, "feed_info.txt", 1, "feed_contact_email") == "invalid_email", "Invalid contact email not reported")
+	assert(t, findingCode(findings, "feed_info.txt", 2, "") == "multiple_feed_info", "Second row not reported")
+	assert(t, len(findings) == 4, "Wrong number of findings")
+
+	feed.FeedInfo = feed.FeedInfo[:1]
+	feed.FeedInfo[0].StartDate = "20060731"
+	feed.FeedInfo[0].EndDate = "2006-07-01"
+	findings = checkFeedInfo(feed)
+	assert(t, findingCode(findings, "feed_info.txt", 1, "feed_end_date") == "invalid_date", "Invalid end date not reported")
+
+	feed.FeedInfo[0].EndDate = "20060701"
+	findings = checkFeedInfo(feed)
+	assert(t, len(findings) == 1 && findings[0].Code == "invalid_date_range", "End before start not reported")
+}
+
+func TestValidatePathways(t *testing.T) {
+	files := make(map[string]string)
+	for name, s := range testStationFiles {
+		files[name] = s
+	}
+	files["pathways.txt"] = `pathway_id,from_stop_id,to_stop_id,pathway_mode,is_bidirectional
+W1,E1,N1,1,1
+X1,STN,E1,1,1
+X2,N1,N9,8,2
+G1,P1,E1,7,1`
+	files["levels.txt"] = "level_id,level_index\nL0,0"
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	codes := []struct {
+		file  string
+		row   int
+		field string
+		code  string
+	}{
+		{"stops.txt", 5, "level_id", "foreign_key_violation"},
+		{"pathways.txt", 2, "from_stop_id", "pathway_to_station"},
+		{"pathways.txt", 3, "to_stop_id", "foreign_key_violation"},
+		{"pathways.txt", 3, "pathway_mode", "invalid_enum_value"},
+		{"pathways.txt", 3, "is_bidirectional", "invalid_enum_value"},
+		{"pathways.txt", 4, "is_bidirectional", "bidirectional_exit_gate"},
+	}
+	for _, c := range codes {
+		assert(t, findingCode(findings, c.file, c.row, c.field) == c.code, "Missing "+c.code+" for "+c.file+" "+c.field)
+	}
+	assert(t, len(findings) == len(codes), "Wrong number of findings")
+}
+
+func TestValidateFares(t *testing.T) {
+	files := withFares(testFeedFiles)
+	files["routes.txt"] = "route_id,route_short_name,route_long_name,route_type,network_id\nA,17,Mission,3,LOCAL"
+	files["rider_categories.txt"] = `rider_category_id,rider_category_name,is_default_fare_category
+ADULT,Adult,1
+SENIOR,Senior,1`
+	files["fare_products.txt"] = `fare_product_id,rider_category_id,fare_media_id,amount,currency
+SINGLE,ADULT,CARD,2.50,USD
+SINGLE,CHILD,NFC,free,XYZ`
+	files["stop_areas.txt"] = "area_id,stop_id\nEAST,S1\nNORTH,S9"
+	files["route_networks.txt"] = "network_id,route_id\nEXPRESS,Q"
+	files["timeframes.txt"] = `timeframe_group_id,start_time,end_time,service_id
+PEAK,07:00:00,,WE
+LATE,23:00:00,25:00:00,XX`
+	files["fare_leg_rules.txt"] = `leg_group_id,network_id,from_area_id,to_area_id,from_timeframe_group_id,fare_product_id,rule_priority
+LOCAL_LEG,METRO,WEST,NORTH,NIGHT,DAYPASS,-1`
+	files["fare_transfer_rules.txt"] = `from_leg_group_id,to_leg_group_id,transfer_count,duration_limit,duration_limit_type,fare_transfer_type,fare_product_id
+LOCAL_LEG,OTHER_LEG,2,,1,3,NOPE
+LOCAL_LEG,LOCAL_LEG,0,-5,,1,`
+
+	feed, err := LoadFS(makeMapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := Validate(feed)
+	for _, f := range findings {
+		t.Log(f.String())
+	}
+
+	codes := []struct {
+		file  string
+		row   int
+		field string
+		code  string
+	}{
+		{"routes.txt", 1, "network_id", "route_networks_conflict"},
+		{"rider_categories.txt", 2, "is_default_fare_category", "multiple_default_rider_categories"},
+		{"fare_products.txt", 2, "rider_category_id", "foreign_key_violation"},
+		{"fare_products.txt", 2, "fare_media_id", "foreign_key_violation"},
+		{"fare_products.txt", 2, "amount", "invalid_number"},
+		{"fare_products.txt", 2, "currency", "invalid_currency"},
+		{"stop_areas.txt", 1, "area_id", "foreign_key_violation"},
+		{"stop_areas.txt", 2, "stop_id", "foreign_key_violation"},
+		{"route_networks.txt", 1, "network_id", "foreign_key_violation"},
+		{"route_networks.txt", 1, "route_id", "foreign_key_violation"},
+		{"timeframes.txt", 1, "end_time", "missing_timeframe_time"},
+		{"timeframes.txt", 2, "service_id", "foreign_key_violation"},
+		{"timeframes.txt", 2, "end_time", "invalid_time"},
+		{"fare_leg_rules.txt", 1, "network_id", "foreign_key_violation"},
+		{"fare_leg_rules.txt", 1, "from_area_id", "foreign_key_violation"},
+		{"fare_leg_rules.txt", 1, "from_timeframe_group_id", "foreign_key_violation"},
+		{"fare_leg_rules.txt", 1, "fare_product_id", "foreign_key_violation"},
+		{"fare_leg_rules.txt", 1, "rule_priority", "invalid_number"},
+		{"fare_transfer_rules.txt", 1, "to_leg_group_id", "foreign_key_violation"},
+		{"fare_transfer_rules.txt", 1, "fare_product_id", "foreign_key_violation"},
+		{"fare_transfer_rules.txt", 1, "transfer_count", "forbidden_transfer_count"},
+		{"fare_transfer_rules.txt", 1, "duration_limit_type", "forbidden_duration_limit_type"},
+		{"fare_transfer_rules.txt", 1, "fare_transfer_type", "invalid_enum_value"},
+		{"fare_transfer_rules.txt", 2, "transfer_count", "invalid_number"},
+		{"fare_transfer_rules.txt", 2, "duration_limit", "invalid_number"},
+		{"fare_transfer_rules.txt", 2, "duration_limit_type", "missing_duration_limit_type"},
+	}
+	for _, c := range codes {
+		assert(t, findingCode(findings, c.file, c.row, c.field) == c.code, "Missing "+c.code+" for "+c.file+" "+c.field)
+	}
+	assert(t, len(findings) == len(codes), "Wrong number of findings")
+}
@@ -0,0 +1,202 @@
+package gtfs
+
+import (
+	"math"
+	"strconv"
+)
+
+// The fastest believable speed in metres per second for each basic route
+// type. Other route types use defaultMaxSpeed.
+var maxSpeeds = map[string]float64{
+	"0":  100 / 3.6, // Tram
+	"1":  150 / 3.6, // Subway
+	"2":  500 / 3.6, // Rail
+	"3":  150 / 3.6, // Bus
+	"4":  80 / 3.6,  // Ferry
+	"5":  50 / 3.6,  // Cable tram
+	"6":  50 / 3.6,  // Aerial lift
+	"7":  50 / 3.6,  // Funicular
+	"11": 100 / 3.6, // Trolleybus
+	"12": 150 / 3.6, // Monorail
+}
+
+const defaultMaxSpeed = 500 / 3.6
+
+// Many feeds round times to the minute, so consecutive stops at the same time
+// are treated as this far apart when working out speeds
+const minTravelTime = 60
+
+const earthRadius = 6371000
+
+// The great circle distance in metres between two points in degrees
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dlat := (lat2 - lat1) * rad
+	dlon := (lon2 - lon1) * rad
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dlon/2)*math.Sin(dlon/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// The coordinates of a stop, or ok false if it has no valid ones
+func stopPosition(s *Stop) (lat, lon float64, ok bool) {
+	if s == nil {
+		return 0, 0, false
+	}
+
+	lat, err := strconv.ParseFloat(s.Latitude, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	lon, err = strconv.ParseFloat(s.Longitude, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+
+	return lat, lon, true
+}
+
+// Checks the stop times of every trip
+func checkStopTimes(f *Feed) []Finding {
+	var out []Finding
+	for _, t := range f.Trips {
+		out = append(out, ValidateTrip(f, t.Id)...)
+	}
+
+	return out
+}
+
+// Checks that the stop times of a trip have unique stop_sequence values, that
+// times never go backwards, and that the speed implied between consecutive
+// timed stops is believable for the route type
+func ValidateTrip(f *Feed, tripID string) []Finding {
+	var out []Finding
+
+	idx := f.getIndex()
+	sts := idx.stopTimesByTrip[tripID]
+
+	add := func(sev Severity, code string, st *StopTime, field, value, message string) {
+		out = append(out, Finding{sev, code, "stop_times.txt", idx.stopTimeRows[st], field, value, "Trip " + tripID + ": " + message})
+	}
+
+	pair := func(a, b *StopTime) string {
+		return "stop " + a.StopId + " (sequence " + a.StopSequence + ") and stop " + b.StopId + " (sequence " + b.StopSequence + ")"
+	}
+
+	// Sequence numbers, in file order to spot rows out of order
+	sorted := true
+	lastSequence := -1
+	for i, st := range sts {
+		if i > 0 && idx.stopTimeRows[st] < idx.stopTimeRows[sts[i-1]] {
+			sorted = false
+		}
+
+		seq, err := strconv.Atoi(st.StopSequence)
+		if err != nil || seq < 0 {
+			add(SeverityError, "invalid_stop_sequence", st, "stop_sequence", st.StopSequence, "Not a non-negative whole number")
+			continue
+		}
+
+		if seq == lastSequence {
+			add(SeverityError, "duplicate_stop_sequence", st, "stop_sequence", st.StopSequence, "Repeated for "+pair(sts[i-1], st))
+		}
+		lastSequence = seq
+	}
+
+	if !sorted && len(sts) > 0 {
+		add(SeverityInfo, "unsorted_stop_sequence", sts[0], "stop_sequence", sts[0].StopSequence, "Rows are not in stop_sequence order")
+	}
+
+	maxSpeed := defaultMaxSpeed
+	if t := f.Trip(tripID); t != nil {
+		if r := f.Route(t.RouteId); r != nil {
+			if s, ok := maxSpeeds[r.Type]; ok {
+				maxSpeed = s
+			}
+		}
+	}
+
+	// Times and speeds, in stop_sequence order
+	var last *StopTime
+	var lastTime ServiceTime
+	var distance float64
+	var prev *StopTime
+
+	for _, st := range sts {
+		var arrival, departure ServiceTime
+		var hasArrival, hasDeparture bool
+
+		if st.ArrivalTime != "" {
+			v, err := ParseServiceTime(st.ArrivalTime)
+			if err != nil {
+				add(SeverityError, "invalid_time", st, "arrival_time", st.ArrivalTime, "Not a valid time")
+			} else {
+				arrival, hasArrival = v, true
+			}
+		}
+
+		if st.DepartureTime != "" {
+			v, err := ParseServiceTime(st.DepartureTime)
+			if err != nil {
+				add(SeverityError, "invalid_time", st, "departure_time", st.DepartureTime, "Not a valid time")
+			} else {
+				departure, hasDeparture = v, true
+			}
+		}
+
+		if hasArrival && hasDeparture && departure.Before(arrival) {
+			add(SeverityError, "departure_before_arrival", st, "departure_time", st.DepartureTime, "Departs before arriving at "+st.ArrivalTime+" at stop "+st.StopId)
+		}
+
+		if prev != nil {
+			lat1, lon1, ok1 := stopPosition(f.Stop(prev.StopId))
+			lat2, lon2, ok2 := stopPosition(f.Stop(st.StopId))
+			if ok1 && ok2 {
+				distance += haversine(lat1, lon1, lat2, lon2)
+			}
+		}
+		prev = st
+
+		if !hasArrival && !hasDeparture {
+			continue
+		}
+
+		field, value := "arrival_time", st.ArrivalTime
+		if !hasArrival {
+			arrival = departure
+			field, value = "departure_time", st.DepartureTime
+		}
+		if !hasDeparture {
+			departure = arrival
+		}
+
+		if last != nil {
+			if arrival.Before(lastTime) {
+				add(SeverityError, "decreasing_stop_time", st, field, value, "Time goes backwards between "+pair(last, st))
+			} else {
+				seconds := float64(arrival - lastTime)
+				if seconds < minTravelTime {
+					seconds = minTravelTime
+				}
+
+				if speed := distance / seconds; speed > maxSpeed {
+					kmh := strconv.FormatFloat(speed*3.6, 'f', 0, 64)
+					add(SeverityWarning, "fast_travel", st, field, value, "Travels at "+kmh+" km/h between "+pair(last, st))
+				}
+			}
+		}
+
+		last = st
+		lastTime = departure
+		distance = 0
+	}
+
+	return out
+}